@@ -1,73 +1,79 @@
-package cache
-
-import (
-	"ecm-sdk-go/constants"
-	configproto "ecm-sdk-go/proto"
-	util "ecm-sdk-go/utils"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
-)
-
-func GetFileName(cacheDir, cacheFilePrefix string) string {
-	return cacheDir + string(os.PathSeparator) + cacheFilePrefix + "_" + constants.CachFileName
-}
-
-func WriteConfigToFile(cacheDir, cacheFilePrefix, content string) {
-	mkdirIfNecessary(cacheDir)
-	fileName := GetFileName(cacheDir, cacheFilePrefix)
-	err := ioutil.WriteFile(fileName, []byte(content), 0666)
-	if err != nil {
-		log.Printf("[ERROR]:faild to write config  cache:%s ,value:%s ,err:%s \n", fileName, string(content), err.Error())
-	}
-}
-
-func ReadConfigFromFile(cacheDir, cacheFilePrefix string) (string, error) {
-	fileName := GetFileName(cacheDir, cacheFilePrefix)
-	b, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		return "", errors.New(fmt.Sprintf("failed to read config cache file:%s,err:%s! ", fileName, err.Error()))
-	}
-	return string(b), nil
-}
-
-func WriteConfigToCache(cachePath, appGroupName, configName string, serviceConfig *configproto.Config) {
-	// write raw config to cache
-	content, err := json.Marshal(serviceConfig)
-	if err != nil {
-		log.Printf("[client.grpc_client] json marshal failed: " + err.Error())
-		return
-	}
-	WriteConfigToFile(cachePath, util.GetServiceConfigKey(appGroupName, configName), string(content))
-
-	// write key value config to cache
-	keyValueConfig := util.GetKeyValueConfig(serviceConfig)
-	if keyValueConfig == nil {
-		return
-	}
-
-	keyContent, err := json.Marshal(keyValueConfig)
-	if err != nil {
-		log.Printf("[client.grpc_client] json marshal failed: " + err.Error())
-		return
-	}
-	WriteConfigToFile(cachePath, util.GetServiceConfigKeyPrefix(appGroupName, configName), string(keyContent))
-}
-
-func ReadConfigFromCache(cachePath, appGroupName, configName string) (*configproto.Config, error) {
-	content, err := ReadConfigFromFile(cachePath, util.GetServiceConfigKey(appGroupName, configName))
-	if err != nil {
-		return nil, err
-	}
-
-	serviceConfig := &configproto.Config{}
-	if err := json.Unmarshal([]byte(content), serviceConfig); err != nil {
-		log.Printf("[client.readConfigFromCache] json unmarshal failed")
-		return nil, err
-	}
-
-	return serviceConfig, nil
-}
+package cache
+
+import (
+	"context"
+	"ecm-sdk-go/constants"
+	configproto "ecm-sdk-go/proto"
+	util "ecm-sdk-go/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+func GetFileName(cacheDir, cacheFilePrefix string) string {
+	return cacheDir + string(os.PathSeparator) + cacheFilePrefix + "_" + constants.CachFileName
+}
+
+// WriteConfigToFile replaces the cache file atomically, the replaced file is kept
+// as the previous generation which is read when the new one is corrupted
+func WriteConfigToFile(cacheDir, cacheFilePrefix, content string) {
+	if err := writeConfigFile(cacheDir, cacheFilePrefix, content); err != nil {
+		log.Printf("[ERROR]:faild to write config  cache:%s ,err:%s \n", GetFileName(cacheDir, cacheFilePrefix), err.Error())
+	}
+}
+
+func writeConfigFile(cacheDir, cacheFilePrefix, content string) error {
+	if err := mkdirIfNecessary(cacheDir); err != nil {
+		return err
+	}
+	return writeFileAtomic(GetFileName(cacheDir, cacheFilePrefix), encodeEntry(content))
+}
+
+// ReadConfigFromFile returns the content of the cache file, or of its previous generation
+// when the checksum of the file does not match
+func ReadConfigFromFile(cacheDir, cacheFilePrefix string) (string, error) {
+	fileName := GetFileName(cacheDir, cacheFilePrefix)
+	content, err := readFileWithFallback(fileName)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("failed to read config cache file:%s,err:%s! ", fileName, err.Error()))
+	}
+	return content, nil
+}
+
+func ReadConfigFromCache(cachePath, appGroupName, configName string) (*configproto.Config, error) {
+	return ReadConfigFromCacheContext(context.Background(), cachePath, appGroupName, configName)
+}
+
+// ReadConfigFromCacheContext is ReadConfigFromCache which gives up when the context is done
+func ReadConfigFromCacheContext(ctx context.Context, cachePath, appGroupName, configName string) (*configproto.Config, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	content, err := ReadConfigFromFile(cachePath, util.GetServiceConfigKey(appGroupName, configName))
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig := &configproto.Config{}
+	if err := json.Unmarshal([]byte(content), serviceConfig); err != nil {
+		log.Printf("[client.readConfigFromCache] json unmarshal failed")
+		return nil, err
+	}
+
+	return serviceConfig, nil
+}
+
+// Cache keeps the last received config of every app group and config,
+// it is read when the server can not be reached
+type Cache interface {
+	Write(appGroupName, configName string, serviceConfig *configproto.Config)
+	Read(ctx context.Context, appGroupName, configName string) (*configproto.Config, error)
+}
+
+// NewFileCache returns the default Cache which writes json files under cachePath
+func NewFileCache(cachePath string) *StoreCache {
+	return NewStoreCache(NewFileStore(cachePath))
+}
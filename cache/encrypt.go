@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"bytes"
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/secret"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// Cipher encrypts the content of the cache files, *secret.AESGCM implements it
+type Cipher interface {
+	secret.Encrypter
+	secret.Decrypter
+}
+
+// NewCipherFromEnv creates the cache cipher from the base64 key in ENSAASMESH_CACHE_KEY,
+// or from the key file named by ENSAASMESH_CACHE_KEY_FILE. It returns nil when neither is set.
+func NewCipherFromEnv() (Cipher, error) {
+	return cipherFromEnv(constants.CacheKeyEnvVar, constants.CacheKeyFileEnvVar, "NewCipherFromEnv")
+}
+
+// NewOldCipherFromEnv creates the cipher of the previous cache key from ENSAASMESH_CACHE_KEY_OLD,
+// or from the key file named by ENSAASMESH_CACHE_KEY_OLD_FILE. It returns nil when neither is set.
+func NewOldCipherFromEnv() (Cipher, error) {
+	return cipherFromEnv(constants.CacheKeyOldEnvVar, constants.CacheKeyOldFileEnvVar, "NewOldCipherFromEnv")
+}
+
+func cipherFromEnv(keyEnvVar, keyFileEnvVar, caller string) (Cipher, error) {
+	if encoded := os.Getenv(keyEnvVar); encoded != "" {
+		key, err := secret.ParseKey(encoded)
+		if err != nil {
+			return nil, errors.New("[cache." + caller + "] " + keyEnvVar + ": " + err.Error())
+		}
+		return secret.NewAESGCM(key)
+	}
+	if keyFile := os.Getenv(keyFileEnvVar); keyFile != "" {
+		return secret.NewAESGCMFromKeyFile(keyFile)
+	}
+	return nil, nil
+}
+
+// keyRing encrypts with the current cipher and decrypts with the current or the previous one,
+// a rekey which stopped halfway leaves entries of both keys
+type keyRing struct {
+	current  Cipher
+	previous Cipher
+}
+
+func (r *keyRing) Encrypt(plaintext string) (string, error) {
+	if r.current == nil {
+		return "", errors.New("no cache key is set")
+	}
+	return r.current.Encrypt(plaintext)
+}
+
+func (r *keyRing) Decrypt(payload string) (string, error) {
+	if r.current != nil {
+		if plaintext, err := r.current.Decrypt(payload); err == nil {
+			return plaintext, nil
+		}
+	}
+	if r.previous == nil {
+		return "", errors.New("the cache is encrypted with another key")
+	}
+	return r.previous.Decrypt(payload)
+}
+
+// sealContent encrypts the content as ENC[...], it is unchanged without cipher
+func sealContent(cipher Cipher, content string) (string, error) {
+	if cipher == nil {
+		return content, nil
+	}
+	return secret.Wrap(cipher, content)
+}
+
+// openContent decrypts an ENC[...] content, plain json written before the key was set is returned as it is
+func openContent(cipher Cipher, content string) (string, error) {
+	if !secret.IsEncrypted(content) {
+		return content, nil
+	}
+	if cipher == nil {
+		return "", errors.New("the cache is encrypted and no cache key is set")
+	}
+	return secret.Unwrap(cipher, content)
+}
+
+// removePrevious deletes the previous generation of the file, after a rekey it holds the old key or plaintext
+func removePrevious(fileName string) error {
+	if err := os.Remove(previousFileName(fileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// isPlaintextFile reports whether the cache file exists and is not encrypted, a corrupted file is read as it is
+func isPlaintextFile(fileName string) bool {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return false
+	}
+	if bytes.HasPrefix(b, []byte(entryHeader)) {
+		if newline := bytes.IndexByte(b, '\n'); newline >= 0 {
+			b = b[newline+1:]
+		}
+	}
+	return !bytes.HasPrefix(b, []byte(secret.EncryptedPrefix))
+}
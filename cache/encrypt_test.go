@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/secret"
+)
+
+func newTestCipher(t *testing.T) Cipher {
+	encoded, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secret.ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := secret.NewAESGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+func putColliding(t *testing.T, store Store) {
+	for _, key := range collidingKeys {
+		if err := store.Put(key.AppGroupName, key.ConfigName, testEntry(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkReadable lists and reads every colliding config with the store
+func checkReadable(t *testing.T, store Store) {
+	keys, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, collidingKeys) {
+		t.Fatalf("List = %v, want %v", keys, collidingKeys)
+	}
+	for _, key := range collidingKeys {
+		entry, err := store.Get(context.Background(), key.AppGroupName, key.ConfigName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Config.Version != testEntry(key).Config.Version {
+			t.Fatalf("Get %v = %+v", key, entry.Config)
+		}
+	}
+}
+
+func TestFileStoreRekey(t *testing.T) {
+	dir := t.TempDir()
+	oldCipher, newCipher := newTestCipher(t), newTestCipher(t)
+	putColliding(t, &FileStore{Dir: dir, Cipher: oldCipher})
+	// a second write keeps a previous generation encrypted with the old key
+	putColliding(t, &FileStore{Dir: dir, Cipher: oldCipher})
+
+	store := &FileStore{Dir: dir, Cipher: oldCipher}
+	if err := store.Rekey(newCipher); err != nil {
+		t.Fatal(err)
+	}
+	checkReadable(t, &FileStore{Dir: dir, Cipher: newCipher})
+	if _, err := (&FileStore{Dir: dir, Cipher: oldCipher}).List(); err == nil {
+		t.Fatal("the old key still reads the store")
+	}
+	previous, _ := filepath.Glob(filepath.Join(dir, entriesDirName, "*"+previousSuffix))
+	if len(previous) != 0 {
+		t.Fatalf("previous generations of the old key are left: %v", previous)
+	}
+}
+
+func TestFileStoreRekeyFrom(t *testing.T) {
+	dir := t.TempDir()
+	oldCipher, newCipher := newTestCipher(t), newTestCipher(t)
+	putColliding(t, &FileStore{Dir: dir, Cipher: oldCipher})
+
+	// the rekey runs on every start while the old key is set, entries of the new key are kept
+	for i := 0; i < 2; i++ {
+		if err := (&FileStore{Dir: dir, Cipher: newCipher}).RekeyFrom(oldCipher); err != nil {
+			t.Fatal(err)
+		}
+		checkReadable(t, &FileStore{Dir: dir, Cipher: newCipher})
+	}
+
+	// without new key the entries are rewritten in plaintext
+	if err := (&FileStore{Dir: dir}).RekeyFrom(newCipher); err != nil {
+		t.Fatal(err)
+	}
+	checkReadable(t, NewFileStore(dir))
+
+	// a wrong old key fails and leaves the store as it is
+	if err := (&FileStore{Dir: dir, Cipher: newCipher}).RekeyFrom(oldCipher); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&FileStore{Dir: dir, Cipher: oldCipher}).RekeyFrom(oldCipher); err == nil {
+		t.Fatal("rekey with the wrong keys succeeded")
+	}
+	checkReadable(t, &FileStore{Dir: dir, Cipher: newCipher})
+}
+
+func TestFileStoreRekeyFromPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	plain := NewFileStore(dir)
+	putColliding(t, plain)
+	// a second write leaves a plaintext previous generation
+	putColliding(t, plain)
+	writeLegacyFiles(t, dir, "legacy", "cfg")
+
+	store := &FileStore{Dir: dir, Cipher: newTestCipher(t)}
+	if err := store.RekeyFrom(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkReadable(t, store)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if isPlaintextFile(path) {
+			t.Errorf("%s is left in plaintext", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), "legacy", "cfg"); err != ErrNotFound {
+		t.Fatalf("Get of the removed legacy config = %v", err)
+	}
+}
+
+func TestFileStorePutRemovesPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	key := Key{AppGroupName: "app", ConfigName: "cfg"}
+	if err := NewFileStore(dir).Put(key.AppGroupName, key.ConfigName, testEntry(key)); err != nil {
+		t.Fatal(err)
+	}
+	writeLegacyFiles(t, dir, key.AppGroupName, key.ConfigName)
+
+	store := &FileStore{Dir: dir, Cipher: newTestCipher(t)}
+	if err := store.Put(key.AppGroupName, key.ConfigName, testEntry(key)); err != nil {
+		t.Fatal(err)
+	}
+	for _, fileName := range store.fileNames(key.AppGroupName, key.ConfigName) {
+		if isPlaintextFile(fileName) || isPlaintextFile(previousFileName(fileName)) {
+			t.Fatalf("%s is left in plaintext", fileName)
+		}
+	}
+}
+
+func TestSingleFileStoreRekey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	oldCipher, newCipher := newTestCipher(t), newTestCipher(t)
+	store := NewSingleFileStore(path)
+	store.Cipher = oldCipher
+	putColliding(t, store)
+
+	if err := store.Rekey(newCipher); err != nil {
+		t.Fatal(err)
+	}
+	reopened := NewSingleFileStore(path)
+	reopened.Cipher = newCipher
+	checkReadable(t, reopened)
+}
+
+func TestNewOldCipherFromEnv(t *testing.T) {
+	encoded, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(constants.CacheKeyOldEnvVar, encoded)
+	defer os.Unsetenv(constants.CacheKeyOldEnvVar)
+
+	cipher, err := NewOldCipherFromEnv()
+	if err != nil || cipher == nil {
+		t.Fatalf("NewOldCipherFromEnv = %v, %v", cipher, err)
+	}
+
+	os.Setenv(constants.CacheKeyOldEnvVar, "not base64")
+	if _, err := NewOldCipherFromEnv(); err == nil {
+		t.Fatal("an invalid old key was accepted")
+	}
+}
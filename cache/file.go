@@ -1,48 +1,174 @@
-package cache
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-)
-
-func GetCurrentPath() string {
-
-	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-	if err != nil {
-		fmt.Println("can not get current path, exit!!")
-		os.Exit(1)
-	}
-
-	return dir
-}
-
-func mkdirIfNecessary(createDir string) error {
-	var path string
-	var err error
-	if os.IsPathSeparator('\\') { //前边的判断是否是系统的分隔符
-		path = "\\"
-	} else {
-		path = "/"
-	}
-
-	s := strings.Split(createDir, path)
-	startIndex := 0
-	dir := ""
-	if s[0] == "" {
-		startIndex = 1
-	} else {
-		dir, _ = os.Getwd() //当前的目录
-	}
-	for i := startIndex; i < len(s); i++ {
-		d := dir + path + strings.Join(s[startIndex:i+1], path)
-		if _, e := os.Stat(d); os.IsNotExist(e) {
-			err = os.Mkdir(d, os.ModePerm) //在当前目录下生成md目录
-			if err != nil {
-				break
-			}
-		}
-	}
-	return err
-}
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FileMode is the mode of the cache files, they hold the private config
+	FileMode os.FileMode = 0600
+	// DirMode is the mode of the cache dirs
+	DirMode os.FileMode = 0700
+
+	// entryHeader starts the first line of a cache file, followed by the sha256 of the content
+	entryHeader = "#ecm-cache sha256:"
+	// previousSuffix is appended to the name of the previous generation of a cache file
+	previousSuffix = ".prev"
+)
+
+func GetCurrentPath() string {
+
+	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
+	if err != nil {
+		fmt.Println("can not get current path, exit!!")
+		os.Exit(1)
+	}
+
+	return dir
+}
+
+func mkdirIfNecessary(createDir string) error {
+	var path string
+	var err error
+	if os.IsPathSeparator('\\') { //前边的判断是否是系统的分隔符
+		path = "\\"
+	} else {
+		path = "/"
+	}
+
+	s := strings.Split(createDir, path)
+	startIndex := 0
+	dir := ""
+	if s[0] == "" {
+		startIndex = 1
+	} else {
+		dir, _ = os.Getwd() //当前的目录
+	}
+	for i := startIndex; i < len(s); i++ {
+		d := dir + path + strings.Join(s[startIndex:i+1], path)
+		if _, e := os.Stat(d); os.IsNotExist(e) {
+			err = os.Mkdir(d, DirMode) //在当前目录下生成md目录
+			if err != nil {
+				break
+			}
+		}
+	}
+	return err
+}
+
+// readFileWithFallback reads the cache file, or its previous generation when the checksum does not match
+func readFileWithFallback(fileName string) (string, error) {
+	content, err := readEntry(fileName)
+	if err == nil {
+		return content, nil
+	}
+	if !os.IsNotExist(err) {
+		log.Printf("[cache.readFile] %s, reading the previous generation", err.Error())
+	}
+
+	if previous, prevErr := readEntry(previousFileName(fileName)); prevErr == nil {
+		return previous, nil
+	}
+	return "", err
+}
+
+// fileExists reports whether the cache file or its previous generation exists
+func fileExists(fileName string) bool {
+	for _, name := range []string{fileName, previousFileName(fileName)} {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func previousFileName(fileName string) string {
+	return fileName + previousSuffix
+}
+
+// encodeEntry prefixes the content with its checksum
+func encodeEntry(content string) []byte {
+	sum := sha256.Sum256([]byte(content))
+	return []byte(entryHeader + hex.EncodeToString(sum[:]) + "\n" + content)
+}
+
+// readEntry reads a cache file and checks its checksum, files written without header are returned as they are
+func readEntry(fileName string) (string, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(b, []byte(entryHeader)) {
+		return string(b), nil
+	}
+
+	newline := bytes.IndexByte(b, '\n')
+	if newline < 0 {
+		return "", errors.New("corrupted cache file " + fileName + ": truncated header")
+	}
+	content := b[newline+1:]
+	sum := sha256.Sum256(content)
+	if string(b[len(entryHeader):newline]) != hex.EncodeToString(sum[:]) {
+		return "", errors.New("corrupted cache file " + fileName + ": checksum mismatch")
+	}
+	return string(content), nil
+}
+
+// writeFileAtomic writes a temp file, syncs it and renames it over fileName.
+// A valid fileName becomes the previous generation first.
+func writeFileAtomic(fileName string, data []byte) error {
+	dir := filepath.Dir(fileName)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if err := tmp.Chmod(FileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// keep the last good generation, a corrupted file must not replace it
+	if _, err := readEntry(fileName); err == nil {
+		if err := os.Rename(fileName, previousFileName(fileName)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmpName, fileName); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir persists the renames in the dir, not every platform supports it
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"context"
+	"ecm-sdk-go/constants"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+	util "ecm-sdk-go/utils"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// entriesDirName is the dir of the entry files under FileStore.Dir, the legacy cache files
+// all end with "_config" so it can not collide with them
+const entriesDirName = "entries"
+
+// FileStore keeps every config in one json file under Dir/entries, which records the app group
+// and config names next to the raw config, the key value form, the history and the fetch time.
+// The file is named after the escaped names, so names containing "_" or any other character do not collide.
+// The files are encrypted when Cipher is set, files written before are still read, see RekeyFrom.
+// A config which has no entry file yet is read from the legacy "<app>_<config>_config" files
+// of earlier versions, the first Put of the config removes them.
+type FileStore struct {
+	Dir    string
+	Cipher Cipher
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+func (s *FileStore) Get(ctx context.Context, appGroupName, configName string) (*Entry, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	entryDir, prefix := s.entryDir(), entryFilePrefix(appGroupName, configName)
+	if !fileExists(GetFileName(entryDir, prefix)) {
+		return s.getLegacy(appGroupName, configName)
+	}
+
+	stored, err := s.readEntry(entryDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if stored.AppGroupName != appGroupName || stored.ConfigName != configName {
+		return nil, ErrNotFound
+	}
+	if stored.Config == nil {
+		stored.Config = &configproto.Config{}
+	}
+	return &stored.Entry, nil
+}
+
+func (s *FileStore) Put(appGroupName, configName string, entry *Entry) error {
+	stored := &storedEntry{AppGroupName: appGroupName, ConfigName: configName, Entry: *entry}
+	if stored.Config == nil {
+		stored.Config = &configproto.Config{}
+	}
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealContent(s.Cipher, string(content))
+	if err != nil {
+		return err
+	}
+	fileName := GetFileName(s.entryDir(), entryFilePrefix(appGroupName, configName))
+	if err := writeConfigFile(s.entryDir(), entryFilePrefix(appGroupName, configName), sealed); err != nil {
+		return err
+	}
+
+	// the plaintext previous generation and legacy files must not outlive the first encrypted write
+	if s.Cipher != nil && isPlaintextFile(previousFileName(fileName)) {
+		if err := removePrevious(fileName); err != nil {
+			return err
+		}
+	}
+	for _, legacy := range s.fileNames(appGroupName, configName)[1:] {
+		for _, name := range []string{legacy, previousFileName(legacy)} {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(appGroupName, configName string) error {
+	for _, fileName := range s.fileNames(appGroupName, configName) {
+		for _, name := range []string{fileName, previousFileName(fileName)} {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// List returns the configs of the entry files, the names are read from the files
+func (s *FileStore) List() ([]Key, error) {
+	files, err := ioutil.ReadDir(s.entryDir())
+	if os.IsNotExist(err) {
+		return []Key{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []Key{}
+	suffix := "_" + constants.CachFileName
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		stored, err := s.readEntry(s.entryDir(), strings.TrimSuffix(name, suffix))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, Key{AppGroupName: stored.AppGroupName, ConfigName: stored.ConfigName})
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+// Rekey rewrites every entry with the cipher, nil writes them in plaintext. All entries are read
+// with the current Cipher before the first one is rewritten, and the previous generations are removed
+// as they hold the old key. With a cipher the plaintext legacy files are removed too, their configs
+// are fetched again. It must not run concurrently with Put.
+func (s *FileStore) Rekey(cipher Cipher) error {
+	keys, err := s.List()
+	if err != nil {
+		return errors.New("[cache.FileStore.Rekey] " + err.Error())
+	}
+	entries := make([]*Entry, len(keys))
+	for i, key := range keys {
+		if entries[i], err = s.Get(context.Background(), key.AppGroupName, key.ConfigName); err != nil {
+			return errors.New("[cache.FileStore.Rekey] read " + key.AppGroupName + "/" + key.ConfigName + " failed: " + err.Error())
+		}
+	}
+
+	s.Cipher = cipher
+	for i, key := range keys {
+		if err := s.Put(key.AppGroupName, key.ConfigName, entries[i]); err != nil {
+			return err
+		}
+		if err := removePrevious(GetFileName(s.entryDir(), entryFilePrefix(key.AppGroupName, key.ConfigName))); err != nil {
+			return err
+		}
+	}
+	if cipher != nil {
+		return s.removeLegacyFiles()
+	}
+	return nil
+}
+
+// RekeyFrom rewrites the entries encrypted with the old cipher, or already with Cipher, with Cipher.
+// A nil old cipher means the cache was written in plaintext, only the plaintext entries are encrypted then
+// and the plaintext previous generations and legacy files are removed. The client runs it on open
+// when a key is set, see config.WithCacheCipher and config.WithCacheRekey.
+func (s *FileStore) RekeyFrom(old Cipher) error {
+	if old == nil {
+		return s.sealPlaintext()
+	}
+	cipher := s.Cipher
+	s.Cipher = &keyRing{current: cipher, previous: old}
+	if err := s.Rekey(cipher); err != nil {
+		s.Cipher = cipher
+		return err
+	}
+	return nil
+}
+
+// sealPlaintext encrypts the entries written in plaintext with Cipher, an entry whose previous generation
+// is plaintext is rewritten too so no plaintext generation is left
+func (s *FileStore) sealPlaintext() error {
+	if s.Cipher == nil {
+		return nil
+	}
+	keys, err := s.List()
+	if err != nil {
+		return errors.New("[cache.FileStore.RekeyFrom] " + err.Error())
+	}
+	for _, key := range keys {
+		fileName := GetFileName(s.entryDir(), entryFilePrefix(key.AppGroupName, key.ConfigName))
+		if !isPlaintextFile(fileName) && !isPlaintextFile(previousFileName(fileName)) {
+			continue
+		}
+		entry, err := s.Get(context.Background(), key.AppGroupName, key.ConfigName)
+		if err != nil {
+			return errors.New("[cache.FileStore.RekeyFrom] read " + key.AppGroupName + "/" + key.ConfigName + " failed: " + err.Error())
+		}
+		if err := s.Put(key.AppGroupName, key.ConfigName, entry); err != nil {
+			return err
+		}
+		if err := removePrevious(fileName); err != nil {
+			return err
+		}
+	}
+	return s.removeLegacyFiles()
+}
+
+// removeLegacyFiles removes the plaintext legacy files of every config, the legacy file names do not tell
+// the app group and config names apart so they can not be moved to entry files
+func (s *FileStore) removeLegacyFiles() error {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	suffix := "_" + constants.CachFileName
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !(strings.HasSuffix(name, suffix) || strings.HasSuffix(name, suffix+previousSuffix)) {
+			continue
+		}
+		if fileName := filepath.Join(s.Dir, name); isPlaintextFile(fileName) {
+			if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getLegacy reads the legacy raw and key value files, their fetch time is the modification time
+func (s *FileStore) getLegacy(appGroupName, configName string) (*Entry, error) {
+	prefix := util.GetServiceConfigKey(appGroupName, configName)
+	if !fileExists(GetFileName(s.Dir, prefix)) {
+		return nil, ErrNotFound
+	}
+
+	entry := &Entry{Config: &configproto.Config{}}
+	if err := s.readJSON(prefix, entry.Config); err != nil {
+		return nil, err
+	}
+
+	keyValuePrefix := util.GetServiceConfigKeyPrefix(appGroupName, configName)
+	if fileExists(GetFileName(s.Dir, keyValuePrefix)) {
+		entry.KeyValue = &types.KeyValueConfig{}
+		if err := s.readJSON(keyValuePrefix, entry.KeyValue); err != nil {
+			entry.KeyValue = nil
+		}
+	}
+
+	if info, err := os.Stat(GetFileName(s.Dir, prefix)); err == nil {
+		entry.FetchedAt = info.ModTime()
+	}
+	return entry, nil
+}
+
+func (s *FileStore) entryDir() string {
+	return filepath.Join(s.Dir, entriesDirName)
+}
+
+// entryFilePrefix escapes the names, "@" separates them as it is escaped inside the names
+func entryFilePrefix(appGroupName, configName string) string {
+	return url.QueryEscape(appGroupName) + "@" + url.QueryEscape(configName)
+}
+
+// fileNames are the entry file and the legacy raw and key value files of the config
+func (s *FileStore) fileNames(appGroupName, configName string) []string {
+	return []string{
+		GetFileName(s.entryDir(), entryFilePrefix(appGroupName, configName)),
+		GetFileName(s.Dir, util.GetServiceConfigKey(appGroupName, configName)),
+		GetFileName(s.Dir, util.GetServiceConfigKeyPrefix(appGroupName, configName)),
+	}
+}
+
+func (s *FileStore) readEntry(dir, prefix string) (*storedEntry, error) {
+	content, err := ReadConfigFromFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if content, err = openContent(s.Cipher, content); err != nil {
+		return nil, errors.New("[cache.FileStore] " + GetFileName(dir, prefix) + ": " + err.Error())
+	}
+	stored := &storedEntry{}
+	if err := json.Unmarshal([]byte(content), stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+func (s *FileStore) readJSON(prefix string, out interface{}) error {
+	content, err := ReadConfigFromFile(s.Dir, prefix)
+	if err != nil {
+		return err
+	}
+	if content, err = openContent(s.Cipher, content); err != nil {
+		return errors.New("[cache.FileStore] " + GetFileName(s.Dir, prefix) + ": " + err.Error())
+	}
+	return json.Unmarshal([]byte(content), out)
+}
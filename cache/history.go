@@ -0,0 +1,37 @@
+package cache
+
+import (
+	configproto "ecm-sdk-go/proto"
+	"time"
+)
+
+// HistoryEntry is one received version of the private object of a config
+type HistoryEntry struct {
+	Version    string    `json:"version"`
+	Format     string    `json:"format"`
+	Private    string    `json:"private"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// appendHistory adds the private object of the config in front of the history when its version is new,
+// only the newest limit versions are kept
+func appendHistory(history []HistoryEntry, serviceConfig *configproto.Config, limit int) []HistoryEntry {
+	if serviceConfig.Version == "" || limit <= 0 {
+		return history
+	}
+	if len(history) > 0 && history[0].Version == serviceConfig.Version {
+		return history
+	}
+
+	entry := HistoryEntry{
+		Version:    serviceConfig.Version,
+		Format:     serviceConfig.Format,
+		Private:    serviceConfig.Private,
+		ReceivedAt: time.Now(),
+	}
+	history = append([]HistoryEntry{entry}, history...)
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return history
+}
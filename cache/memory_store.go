@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	configproto "ecm-sdk-go/proto"
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MemoryStore keeps the configs in memory, for tests and read-only filesystems
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	entries map[Key]*Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[Key]*Entry{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, appGroupName, configName string) (*Entry, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	entry, ok := s.entries[Key{AppGroupName: appGroupName, ConfigName: configName}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyEntry(entry), nil
+}
+
+func (s *MemoryStore) Put(appGroupName, configName string, entry *Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries[Key{AppGroupName: appGroupName, ConfigName: configName}] = copyEntry(entry)
+	return nil
+}
+
+func (s *MemoryStore) Delete(appGroupName, configName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, Key{AppGroupName: appGroupName, ConfigName: configName})
+	return nil
+}
+
+func (s *MemoryStore) List() ([]Key, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	keys := make([]Key, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+// copyEntry keeps the stored entry away from the changes of the caller,
+// the maps of the key value form are not changed after parsing and are shared
+func copyEntry(entry *Entry) *Entry {
+	result := &Entry{
+		KeyValue:  entry.KeyValue,
+		History:   append([]HistoryEntry(nil), entry.History...),
+		FetchedAt: entry.FetchedAt,
+	}
+	if entry.Config != nil {
+		result.Config = proto.Clone(entry.Config).(*configproto.Config)
+	}
+	return result
+}
+
+func sortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].AppGroupName != keys[j].AppGroupName {
+			return keys[i].AppGroupName < keys[j].AppGroupName
+		}
+		return keys[i].ConfigName < keys[j].ConfigName
+	})
+}
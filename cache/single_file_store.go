@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SingleFileStore keeps all configs in one file, which is rewritten atomically on every change.
+// The file is read once, later changes by other processes are not seen.
+// The file is encrypted when Cipher is set before the first use, change it later with Rekey.
+type SingleFileStore struct {
+	Cipher Cipher
+
+	path    string
+	mutex   sync.Mutex
+	entries map[string]*storedEntry
+}
+
+// storedEntry is an entry with the names of its config, as written by SingleFileStore and FileStore
+type storedEntry struct {
+	AppGroupName string `json:"appGroupName"`
+	ConfigName   string `json:"configName"`
+	Entry
+}
+
+// storeKey joins the escaped names, unlike utils.GetServiceConfigKey names containing "_" do not collide
+func storeKey(appGroupName, configName string) string {
+	return url.QueryEscape(appGroupName) + "/" + url.QueryEscape(configName)
+}
+
+func NewSingleFileStore(path string) *SingleFileStore {
+	return &SingleFileStore{path: path}
+}
+
+func (s *SingleFileStore) Get(ctx context.Context, appGroupName, configName string) (*Entry, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	stored, ok := s.entries[storeKey(appGroupName, configName)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyEntry(&stored.Entry), nil
+}
+
+func (s *SingleFileStore) Put(appGroupName, configName string, entry *Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.entries[storeKey(appGroupName, configName)] = &storedEntry{
+		AppGroupName: appGroupName,
+		ConfigName:   configName,
+		Entry:        *copyEntry(entry),
+	}
+	return s.save()
+}
+
+func (s *SingleFileStore) Delete(appGroupName, configName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	key := storeKey(appGroupName, configName)
+	if _, ok := s.entries[key]; !ok {
+		return nil
+	}
+	delete(s.entries, key)
+	return s.save()
+}
+
+func (s *SingleFileStore) List() ([]Key, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	keys := make([]Key, 0, len(s.entries))
+	for _, stored := range s.entries {
+		keys = append(keys, Key{AppGroupName: stored.AppGroupName, ConfigName: stored.ConfigName})
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+// Rekey rewrites the file with the cipher, nil writes it in plaintext. The previous generation
+// is removed as it holds the old key.
+func (s *SingleFileStore) Rekey(cipher Cipher) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.Cipher = cipher
+	if err := s.save(); err != nil {
+		return err
+	}
+	return removePrevious(s.path)
+}
+
+// load reads the file the first time, the caller holds the mutex
+func (s *SingleFileStore) load() error {
+	if s.entries != nil {
+		return nil
+	}
+
+	entries := map[string]*storedEntry{}
+	content, err := readFileWithFallback(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if content, err = openContent(s.Cipher, content); err != nil {
+			return errors.New("[cache.SingleFileStore] " + s.path + ": " + err.Error())
+		}
+		if err := json.Unmarshal([]byte(content), &entries); err != nil {
+			return err
+		}
+	}
+
+	// the map is keyed by the names recorded in the entries, whatever the key in the file
+	s.entries = make(map[string]*storedEntry, len(entries))
+	for _, stored := range entries {
+		s.entries[storeKey(stored.AppGroupName, stored.ConfigName)] = stored
+	}
+	return nil
+}
+
+// save rewrites the file, the caller holds the mutex
+func (s *SingleFileStore) save() error {
+	content, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealContent(s.Cipher, string(content))
+	if err != nil {
+		return err
+	}
+	if err := mkdirIfNecessary(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, encodeEntry(sealed))
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"ecm-sdk-go/constants"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+	util "ecm-sdk-go/utils"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Get when the config is not in the store
+var ErrNotFound = errors.New("config not found in cache")
+
+// Key names a config in a Store
+type Key struct {
+	AppGroupName string
+	ConfigName   string
+}
+
+// Entry is what a Store keeps for one config
+type Entry struct {
+	Config   *configproto.Config   `json:"config"`
+	KeyValue *types.KeyValueConfig `json:"keyValue,omitempty"`
+	// History lists the last received versions, newest first
+	History []HistoryEntry `json:"history,omitempty"`
+	// FetchedAt is when the server last returned the config, zero when unknown
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Store persists the cached configs, FileStore, MemoryStore and SingleFileStore are provided
+// and any other implementation can be set with config.WithStore
+type Store interface {
+	// Get returns ErrNotFound when the config is not in the store
+	Get(ctx context.Context, appGroupName, configName string) (*Entry, error)
+	Put(appGroupName, configName string, entry *Entry) error
+	// Delete ignores a config which is not in the store
+	Delete(appGroupName, configName string) error
+	List() ([]Key, error)
+}
+
+// StoreCache is the Cache of the client on top of a Store, it keeps the key value form
+// and the last HistorySize versions of every config next to the raw config
+type StoreCache struct {
+	Store       Store
+	HistorySize int
+}
+
+func NewStoreCache(store Store) *StoreCache {
+	return &StoreCache{Store: store, HistorySize: constants.HistorySize}
+}
+
+func (c *StoreCache) Write(appGroupName, configName string, serviceConfig *configproto.Config) {
+	entry := &Entry{
+		Config:    serviceConfig,
+		KeyValue:  util.GetKeyValueConfig(serviceConfig),
+		FetchedAt: time.Now(),
+	}
+	if previous, err := c.Store.Get(context.Background(), appGroupName, configName); err == nil {
+		entry.History = previous.History
+	}
+	entry.History = appendHistory(entry.History, serviceConfig, c.HistorySize)
+
+	if err := c.Store.Put(appGroupName, configName, entry); err != nil {
+		log.Printf("[cache.StoreCache] write %s failed: %s", util.GetServiceConfigKey(appGroupName, configName), err.Error())
+	}
+}
+
+func (c *StoreCache) Read(ctx context.Context, appGroupName, configName string) (*configproto.Config, error) {
+	entry, err := c.ReadEntry(ctx, appGroupName, configName)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Config, nil
+}
+
+// ReadEntry is Read which also returns when the config was fetched
+func (c *StoreCache) ReadEntry(ctx context.Context, appGroupName, configName string) (*Entry, error) {
+	entry, err := c.Store.Get(ctx, appGroupName, configName)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Config == nil {
+		return nil, ErrNotFound
+	}
+	return entry, nil
+}
+
+func (c *StoreCache) History(appGroupName, configName string) ([]HistoryEntry, error) {
+	entry, err := c.Store.Get(context.Background(), appGroupName, configName)
+	if err == ErrNotFound {
+		return []HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry.History == nil {
+		return []HistoryEntry{}, nil
+	}
+	return entry.History, nil
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	configproto "ecm-sdk-go/proto"
+	util "ecm-sdk-go/utils"
+)
+
+// collidingKeys are distinct configs whose "<app>_<config>" names collide or look like side files
+var collidingKeys = []Key{
+	{AppGroupName: "my", ConfigName: "app_cfg_one"},
+	{AppGroupName: "my_app", ConfigName: "cfg_one"},
+	{AppGroupName: "svc", ConfigName: "x"},
+	{AppGroupName: "svc", ConfigName: "x_history"},
+	{AppGroupName: "svc", ConfigName: "x_keyvalue"},
+	{AppGroupName: "svc", ConfigName: "x_meta"},
+}
+
+func testStores(t *testing.T) map[string]Store {
+	dir := t.TempDir()
+	return map[string]Store{
+		"file":        NewFileStore(filepath.Join(dir, "files")),
+		"memory":      NewMemoryStore(),
+		"single file": NewSingleFileStore(filepath.Join(dir, "single", "cache.json")),
+	}
+}
+
+func testEntry(key Key) *Entry {
+	version := key.AppGroupName + "/" + key.ConfigName
+	return &Entry{
+		Config:    &configproto.Config{Private: "name: " + version + "\n", Format: "yaml", Version: version},
+		History:   []HistoryEntry{{Version: version, Format: "yaml", Private: "name: " + version + "\n"}},
+		FetchedAt: time.Unix(1600000000, 0).UTC(),
+	}
+}
+
+func TestStoreNamesWithUnderscore(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range collidingKeys {
+				if err := store.Put(key.AppGroupName, key.ConfigName, testEntry(key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			keys, err := store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(keys, collidingKeys) {
+				t.Fatalf("List = %v, want %v", keys, collidingKeys)
+			}
+			for _, key := range collidingKeys {
+				entry, err := store.Get(context.Background(), key.AppGroupName, key.ConfigName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := testEntry(key)
+				if entry.Config.Version != want.Config.Version || !reflect.DeepEqual(entry.History, want.History) || !entry.FetchedAt.Equal(want.FetchedAt) {
+					t.Fatalf("Get %v = %+v", key, entry)
+				}
+			}
+
+			if err := store.Delete("my_app", "cfg_one"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(context.Background(), "my_app", "cfg_one"); err != ErrNotFound {
+				t.Fatalf("Get of the deleted config = %v", err)
+			}
+			if _, err := store.Get(context.Background(), "my", "app_cfg_one"); err != nil {
+				t.Fatalf("Get of the colliding config = %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreReadsLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeLegacyFiles(t, dir, "app", "cfg")
+
+	store := NewFileStore(dir)
+	entry, err := store.Get(context.Background(), "app", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Config.Version != "v1" || entry.KeyValue == nil || entry.FetchedAt.IsZero() {
+		t.Fatalf("legacy entry = %+v", entry)
+	}
+
+	// the next write moves the config to its entry file
+	if err := store.Put("app", "cfg", testEntry(Key{AppGroupName: "app", ConfigName: "cfg"})); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err = store.Get(context.Background(), "app", "cfg"); err != nil || entry.Config.Version != "app/cfg" {
+		t.Fatalf("Get = %+v, %v", entry, err)
+	}
+	if err := store.Delete("app", "cfg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), "app", "cfg"); err != ErrNotFound {
+		t.Fatalf("Get of the deleted config = %v", err)
+	}
+}
+
+// writeLegacyFiles writes the plaintext raw and key value files earlier versions kept per config
+func writeLegacyFiles(t *testing.T, dir, appGroupName, configName string) {
+	serviceConfig := &configproto.Config{Private: "a: 1\n", Format: "yaml", Version: "v1"}
+	content, err := json.Marshal(serviceConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyValue, err := json.Marshal(util.GetKeyValueConfig(serviceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(dir, util.GetServiceConfigKey(appGroupName, configName), string(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(dir, util.GetServiceConfigKeyPrefix(appGroupName, configName), string(keyValue)); err != nil {
+		t.Fatal(err)
+	}
+}
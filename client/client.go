@@ -1,374 +1,445 @@
-package client
-
-import (
-	"ecm-sdk-go/config"
-	configproto "ecm-sdk-go/proto"
-	"ecm-sdk-go/types"
-	"ecm-sdk-go/utils"
-	"errors"
-	"log"
-
-	"k8s.io/apimachinery/pkg/util/json"
-)
-
-type ConfigClient struct {
-	serviceConfig map[string]*configproto.Config
-	grpcClient    *GrpcClient
-}
-
-func NewConfigClient(config *config.Config) (ConfigClient, error) {
-	client := ConfigClient{}
-	// init service config
-	client.serviceConfig = map[string]*configproto.Config{}
-
-	clientConfig, err := config.GetClientConfig()
-	if err != nil {
-		return client, err
-	}
-
-	// get Grpc Client
-	grpcClient, err := newGrpcClient(clientConfig)
-	if err != nil {
-		log.Printf("[client.client] grpc server cannot be connected %s", err.Error())
-		return client, err
-	}
-	client.grpcClient = grpcClient
-
-	return client, err
-}
-
-func (client *ConfigClient) DeleteConfigClient() {
-	if client.grpcClient != nil {
-		client.grpcClient.deleteGrpcClient()
-	}
-}
-
-func (client *ConfigClient) GetConfig(appGroupName, configName string) (*types.Config, error) {
-	// check service name and group id
-	if appGroupName == "" {
-		var err error
-		appGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return nil, err
-		}
-		if appGroupName == "" {
-			return nil, errors.New("[client.GetConfig] the app group name can not be empty")
-		}
-	}
-
-	if configName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return nil, err
-		}
-		if len(configNames) == 1 {
-			configName = configNames[0]
-		}
-		if configName == "" {
-			return nil, errors.New("[client.GetConfig] the config name can not be empty")
-		}
-	}
-
-	serviceKey := utils.GetServiceConfigKey(appGroupName, configName)
-
-	if client.grpcClient != nil {
-		if client.serviceConfig[serviceKey] == nil {
-			client.serviceConfig[serviceKey] = &configproto.Config{}
-		}
-		err := client.grpcClient.getConfig(appGroupName, configName, client.serviceConfig[serviceKey])
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, errors.New("[client.GetConfig] grpc server can not be connected")
-	}
-
-	// json unmarsh services
-	services := map[string]map[string]*types.ServiceAddress{}
-	if client.serviceConfig[serviceKey].Services != "" {
-		if err := json.Unmarshal([]byte(client.serviceConfig[serviceKey].Services), &services); err != nil {
-			return nil, errors.New("[client.GetConfig] JSON unmarshal services failed")
-		}
-	}
-	config := &types.Config{
-		Private:       client.serviceConfig[serviceKey].Private,
-		Version:       client.serviceConfig[serviceKey].Version,
-		Format:        client.serviceConfig[serviceKey].Format,
-		Public:        client.serviceConfig[serviceKey].Public,
-		PublicVersion: client.serviceConfig[serviceKey].PublicVersion,
-		PublicFormat:  client.serviceConfig[serviceKey].PublicFormat,
-		Services:      services,
-	}
-
-	return config, nil
-}
-
-func (client *ConfigClient) GetKeyValueConfig(appGroupName, configName string) (*types.KeyValueConfig, error) {
-	// check service name and group id
-	if appGroupName == "" {
-		var err error
-		appGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return nil, err
-		}
-		if appGroupName == "" {
-			return nil, errors.New("[client.GetKeyValueConfig] the app group name can not be empty")
-		}
-	}
-
-	if configName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return nil, err
-		}
-		if len(configNames) == 1 {
-			configName = configNames[0]
-		}
-		if configName == "" {
-			return nil, errors.New("[client.GetKeyValueConfig] the config name can not be empty")
-		}
-	}
-
-	serviceKey := utils.GetServiceConfigKey(appGroupName, configName)
-
-	if client.grpcClient != nil {
-		if client.serviceConfig[serviceKey] == nil {
-			client.serviceConfig[serviceKey] = &configproto.Config{}
-		}
-		err := client.grpcClient.getConfig(appGroupName, configName, client.serviceConfig[serviceKey])
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, errors.New("[client.GetKeyValueConifg] grpc server can not be connected")
-	}
-
-	return utils.GetKeyValueConfig(client.serviceConfig[serviceKey]), nil
-}
-
-func (client *ConfigClient) GetPublicConfig(appGroupName, configName string) (string, error) {
-	// check service name and group id
-	if appGroupName == "" {
-		var err error
-		appGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return "", err
-		}
-		if appGroupName == "" {
-			return "", errors.New("[client.GetPublicConfig] the app group name can not be empty")
-		}
-	}
-
-	if configName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return "", err
-		}
-		if len(configNames) == 1 {
-			configName = configNames[0]
-		}
-		if configName == "" {
-			return "", errors.New("[client.GetPublicConfig] the config name can not be empty")
-		}
-	}
-
-	var public string
-	serviceKey := utils.GetServiceConfigKey(appGroupName, configName)
-
-	if client.grpcClient != nil {
-		if client.serviceConfig[serviceKey] == nil {
-			client.serviceConfig[serviceKey] = &configproto.Config{}
-		}
-
-		err := client.grpcClient.getConfig(appGroupName, configName, client.serviceConfig[serviceKey])
-		if err != nil {
-			return "", err
-		}
-		public = client.serviceConfig[serviceKey].Public
-	} else {
-		return "", errors.New("[client.GetPublicConfig] grpc server can not be connected")
-	}
-
-	return public, nil
-}
-
-func (client *ConfigClient) GetPrivateConfig(appGroupName, configName string) (string, error) {
-	// check service name and group id
-	if appGroupName == "" {
-		var err error
-		appGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return "", err
-		}
-		if appGroupName == "" {
-			return "", errors.New("[client.GetPrivateConfig] the app group name can not be empty")
-		}
-	}
-
-	if configName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return "", err
-		}
-		if len(configNames) == 1 {
-			configName = configNames[0]
-		}
-		if configName == "" {
-			return "", errors.New("[client.GetPrivateConfig] the config name can not be empty")
-		}
-	}
-
-	var private string
-	serviceKey := utils.GetServiceConfigKey(appGroupName, configName)
-
-	if client.grpcClient != nil {
-		if client.serviceConfig[serviceKey] == nil {
-			client.serviceConfig[serviceKey] = &configproto.Config{}
-		}
-		err := client.grpcClient.getConfig(appGroupName, configName, client.serviceConfig[serviceKey])
-		if err != nil {
-			return "", err
-		}
-		private = client.serviceConfig[serviceKey].Private
-	} else {
-		return "", errors.New("[client.GetPrivateConfig] grpc server can not be connected")
-	}
-
-	return private, nil
-}
-
-func (client *ConfigClient) GetServiceAddress(appGroupName, configName, service string) (map[string]*types.ServiceAddress, error) {
-	// check service name and group id
-	if appGroupName == "" {
-		var err error
-		appGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return nil, err
-		}
-		if appGroupName == "" {
-			return nil, errors.New("[client.GetServiceAddress] the app group name can not be empty")
-		}
-	}
-
-	if configName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return nil, err
-		}
-		if len(configNames) == 1 {
-			configName = configNames[0]
-		}
-		if configName == "" {
-			return nil, errors.New("[client.GetServiceAddress] the config name can not be empty")
-		}
-	}
-
-	var serviceAddress map[string]*types.ServiceAddress
-	serviceKey := utils.GetServiceConfigKey(appGroupName, configName)
-
-	if client.grpcClient != nil {
-		if client.serviceConfig[serviceKey] == nil {
-			client.serviceConfig[serviceKey] = &configproto.Config{}
-		}
-		err := client.grpcClient.getConfig(appGroupName, configName, client.serviceConfig[serviceKey])
-		if err != nil {
-			return nil, err
-		}
-		// json unmarsh services
-		services := map[string]map[string]*types.ServiceAddress{}
-		if client.serviceConfig[serviceKey].Services != "" {
-			if err := json.Unmarshal([]byte(client.serviceConfig[serviceKey].Services), &services); err != nil {
-				return nil, errors.New("[client.GetServiceAddress] JSON unmarshal services failed")
-			}
-		}
-		for key, value := range services {
-			if service == key {
-				serviceAddress = value
-				break
-			}
-		}
-	} else {
-		return nil, errors.New("[client.GetServiceAddress] grpc server can not be connected")
-	}
-
-	return serviceAddress, nil
-}
-
-func (client *ConfigClient) PublishConfig(publishConfigRequest *configproto.PublishConfigRequest) error {
-
-	// check service name and group id
-	if publishConfigRequest.AppGroupName == "" {
-		var err error
-		publishConfigRequest.AppGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return err
-		}
-		if publishConfigRequest.AppGroupName == "" {
-			return errors.New("[client.PublishConfig] the app group name name can not be empty")
-		}
-	}
-
-	if publishConfigRequest.ConfigName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return err
-		}
-		if len(configNames) == 1 {
-			publishConfigRequest.ConfigName = configNames[0]
-		}
-		if publishConfigRequest.ConfigName == "" {
-			return errors.New("[client.PublishConfig] the config name can not be empty")
-		}
-	}
-	if client.grpcClient != nil {
-		err := client.grpcClient.publishConfig(publishConfigRequest)
-		if err != nil {
-			return err
-		}
-	} else {
-		return errors.New("[client.PublishConfig] grpc server can not be connected")
-	}
-
-	return nil
-}
-
-func (client *ConfigClient) ListenConfig(param config.ListenConfigParam) error {
-
-	// check service name and group id
-	if param.AppGroupName == "" {
-		var err error
-		param.AppGroupName, err = utils.GetDefaultAppGroupName()
-		if err != nil {
-			return err
-		}
-		if param.AppGroupName == "" {
-			return errors.New("[client.ListenConfig] the app group name can not be empty")
-		}
-	}
-
-	if param.ConfigName == "" {
-		configNames, err := utils.GetDefaultConfigNames()
-		if err != nil {
-			return err
-		}
-		if len(configNames) == 1 {
-			param.ConfigName = configNames[0]
-		}
-		if param.ConfigName == "" {
-			return errors.New("[client.ListenConfig] the config name can not be empty")
-		}
-	}
-
-	serviceKey := utils.GetServiceConfigKey(param.AppGroupName, param.ConfigName)
-
-	if client.grpcClient != nil {
-		if client.serviceConfig[serviceKey] == nil {
-			client.serviceConfig[serviceKey] = &configproto.Config{}
-		}
-		client.grpcClient.listenConfig(client.serviceConfig[serviceKey], &param)
-	} else {
-		return errors.New("[client.ListenConfig] grpc server can not be connected")
-	}
-
-	return nil
-}
+package client
+
+import (
+	"context"
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/decode"
+	"ecm-sdk-go/patch"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+	"ecm-sdk-go/utils"
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+type ConfigClient struct {
+	serviceConfig map[string]*configproto.Config
+	grpcClient    *GrpcClient
+}
+
+// NewConfigClient creates a client from the options, a *config.Config is itself an option
+// so NewConfigClient(&conf) keeps working. Without a config the client config is read from env.
+func NewConfigClient(opts ...config.Option) (ConfigClient, error) {
+	client := ConfigClient{}
+	// init service config
+	client.serviceConfig = map[string]*configproto.Config{}
+
+	options := config.NewOptions(opts...)
+	if options.Config == nil {
+		config.WithClientConfig(config.ClientConfig{}).Apply(options)
+	}
+	if options.ConfigErr != nil {
+		return client, options.ConfigErr
+	}
+
+	clientConfig, err := options.Config.GetClientConfig()
+	if err != nil {
+		return client, err
+	}
+
+	// get Grpc Client
+	grpcClient, err := newGrpcClient(clientConfig, options)
+	if err != nil {
+		options.Logger.Printf("[client.client] grpc server cannot be connected %s", err.Error())
+		return client, err
+	}
+	client.grpcClient = grpcClient
+
+	return client, err
+}
+
+func (client *ConfigClient) DeleteConfigClient() {
+	if client.grpcClient != nil {
+		client.grpcClient.deleteGrpcClient()
+	}
+}
+
+// resolveNames fills the empty app group name and config name from the backend register information
+func resolveNames(ctx context.Context, appGroupName, configName, caller string) (string, string, error) {
+	// check service name and group id
+	if appGroupName == "" {
+		var err error
+		appGroupName, err = utils.GetDefaultAppGroupNameContext(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		if appGroupName == "" {
+			return "", "", errors.New("[client." + caller + "] the app group name can not be empty")
+		}
+	}
+
+	if configName == "" {
+		configNames, err := utils.GetDefaultConfigNamesContext(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		if len(configNames) == 1 {
+			configName = configNames[0]
+		}
+		if configName == "" {
+			return "", "", errors.New("[client." + caller + "] the config name can not be empty")
+		}
+	}
+
+	return appGroupName, configName, nil
+}
+
+// fetchConfig refreshes the service config of the app group and config from the server
+func (client *ConfigClient) fetchConfig(ctx context.Context, appGroupName, configName, caller string) (*configproto.Config, error) {
+	serviceKey := utils.GetServiceConfigKey(appGroupName, configName)
+
+	if client.grpcClient == nil {
+		return nil, errors.New("[client." + caller + "] grpc server can not be connected")
+	}
+
+	if client.serviceConfig[serviceKey] == nil {
+		client.serviceConfig[serviceKey] = &configproto.Config{}
+	}
+	if err := client.grpcClient.getConfig(ctx, appGroupName, configName, client.serviceConfig[serviceKey]); err != nil {
+		return nil, err
+	}
+
+	return client.serviceConfig[serviceKey], nil
+}
+
+func (client *ConfigClient) GetConfig(appGroupName, configName string) (*types.Config, error) {
+	return client.GetConfigContext(context.Background(), appGroupName, configName)
+}
+
+// GetConfigContext is GetConfig with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) GetConfigContext(ctx context.Context, appGroupName, configName string) (*types.Config, error) {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "GetConfig")
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig, err := client.fetchConfig(ctx, appGroupName, configName, "GetConfig")
+	if err != nil {
+		return nil, err
+	}
+
+	// json unmarsh services
+	services := map[string]map[string]*types.ServiceAddress{}
+	if serviceConfig.Services != "" {
+		if err := json.Unmarshal([]byte(serviceConfig.Services), &services); err != nil {
+			return nil, errors.New("[client.GetConfig] JSON unmarshal services failed")
+		}
+	}
+	config := &types.Config{
+		Private:       serviceConfig.Private,
+		Version:       serviceConfig.Version,
+		Format:        serviceConfig.Format,
+		Public:        serviceConfig.Public,
+		PublicVersion: serviceConfig.PublicVersion,
+		PublicFormat:  serviceConfig.PublicFormat,
+		Services:      services,
+		FetchInfo:     client.grpcClient.fetchInfo(appGroupName, configName),
+	}
+
+	return config, nil
+}
+
+// LastFetch tells whether the last read of the config came from the server or the cache and when
+// it was fetched from the server, GetConfig returns it with the config
+func (client *ConfigClient) LastFetch(appGroupName, configName string) (types.FetchInfo, error) {
+	appGroupName, configName, err := resolveNames(context.Background(), appGroupName, configName, "LastFetch")
+	if err != nil {
+		return types.FetchInfo{}, err
+	}
+	if client.grpcClient == nil {
+		return types.FetchInfo{}, errors.New("[client.LastFetch] grpc server can not be connected")
+	}
+	return client.grpcClient.fetchInfo(appGroupName, configName), nil
+}
+
+func (client *ConfigClient) GetKeyValueConfig(appGroupName, configName string) (*types.KeyValueConfig, error) {
+	return client.GetKeyValueConfigContext(context.Background(), appGroupName, configName)
+}
+
+// GetKeyValueConfigContext is GetKeyValueConfig with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) GetKeyValueConfigContext(ctx context.Context, appGroupName, configName string) (*types.KeyValueConfig, error) {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "GetKeyValueConfig")
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig, err := client.fetchConfig(ctx, appGroupName, configName, "GetKeyValueConfig")
+	if err != nil {
+		return nil, err
+	}
+
+	keyValueConfig, err := client.grpcClient.keyValueConfig(serviceConfig)
+	if err != nil {
+		return nil, errors.New("[client.GetKeyValueConfig] " + err.Error())
+	}
+	return keyValueConfig, nil
+}
+
+// GetEffectiveConfig merges public, private and services into one key space,
+// precedence lists the objects from the highest to the lowest, by default private overrides public
+func (client *ConfigClient) GetEffectiveConfig(appGroupName, configName string, precedence ...string) (*types.EffectiveConfig, error) {
+	return client.GetEffectiveConfigContext(context.Background(), appGroupName, configName, precedence...)
+}
+
+// GetEffectiveConfigContext is GetEffectiveConfig with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) GetEffectiveConfigContext(ctx context.Context, appGroupName, configName string, precedence ...string) (*types.EffectiveConfig, error) {
+	keyValueConfig, err := client.GetKeyValueConfigContext(ctx, appGroupName, configName)
+	if err != nil {
+		return nil, err
+	}
+	if keyValueConfig == nil {
+		return nil, errors.New("[client.GetEffectiveConfig] parse key value config failed")
+	}
+
+	return types.NewEffectiveConfig(keyValueConfig, precedence...)
+}
+
+// Unmarshal decodes the effective config into out, a pointer to a struct with `ecm:"key"` tags.
+// Keys are looked up in the private object first, then in the public and services objects.
+func (client *ConfigClient) Unmarshal(appGroupName, configName string, out interface{}) error {
+	return client.UnmarshalContext(context.Background(), appGroupName, configName, out)
+}
+
+// UnmarshalContext is Unmarshal with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) UnmarshalContext(ctx context.Context, appGroupName, configName string, out interface{}) error {
+	effectiveConfig, err := client.GetEffectiveConfigContext(ctx, appGroupName, configName)
+	if err != nil {
+		return err
+	}
+
+	return decode.Unmarshal(effectiveConfig.Values, out)
+}
+
+func (client *ConfigClient) GetPublicConfig(appGroupName, configName string) (string, error) {
+	return client.GetPublicConfigContext(context.Background(), appGroupName, configName)
+}
+
+// GetPublicConfigContext is GetPublicConfig with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) GetPublicConfigContext(ctx context.Context, appGroupName, configName string) (string, error) {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "GetPublicConfig")
+	if err != nil {
+		return "", err
+	}
+
+	serviceConfig, err := client.fetchConfig(ctx, appGroupName, configName, "GetPublicConfig")
+	if err != nil {
+		return "", err
+	}
+
+	return serviceConfig.Public, nil
+}
+
+func (client *ConfigClient) GetPrivateConfig(appGroupName, configName string) (string, error) {
+	return client.GetPrivateConfigContext(context.Background(), appGroupName, configName)
+}
+
+// GetPrivateConfigContext is GetPrivateConfig with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) GetPrivateConfigContext(ctx context.Context, appGroupName, configName string) (string, error) {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "GetPrivateConfig")
+	if err != nil {
+		return "", err
+	}
+
+	serviceConfig, err := client.fetchConfig(ctx, appGroupName, configName, "GetPrivateConfig")
+	if err != nil {
+		return "", err
+	}
+
+	return serviceConfig.Private, nil
+}
+
+func (client *ConfigClient) GetServiceAddress(appGroupName, configName, service string) (map[string]*types.ServiceAddress, error) {
+	return client.GetServiceAddressContext(context.Background(), appGroupName, configName, service)
+}
+
+// GetServiceAddressContext is GetServiceAddress with a context which bounds the rpc and the cache fallback
+func (client *ConfigClient) GetServiceAddressContext(ctx context.Context, appGroupName, configName, service string) (map[string]*types.ServiceAddress, error) {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "GetServiceAddress")
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig, err := client.fetchConfig(ctx, appGroupName, configName, "GetServiceAddress")
+	if err != nil {
+		return nil, err
+	}
+
+	// json unmarsh services
+	var serviceAddress map[string]*types.ServiceAddress
+	services := map[string]map[string]*types.ServiceAddress{}
+	if serviceConfig.Services != "" {
+		if err := json.Unmarshal([]byte(serviceConfig.Services), &services); err != nil {
+			return nil, errors.New("[client.GetServiceAddress] JSON unmarshal services failed")
+		}
+	}
+	for key, value := range services {
+		if service == key {
+			serviceAddress = value
+			break
+		}
+	}
+
+	return serviceAddress, nil
+}
+
+// PublishConfig publishes the private object of the config. With ExpectedVersion set it fails
+// with *VersionConflictError when the config is no longer at that version.
+func (client *ConfigClient) PublishConfig(publishConfigRequest *configproto.PublishConfigRequest) error {
+	return client.PublishConfigContext(context.Background(), publishConfigRequest)
+}
+
+// PublishConfigContext is PublishConfig with a context which bounds the rpc and the reconnect on failure
+func (client *ConfigClient) PublishConfigContext(ctx context.Context, publishConfigRequest *configproto.PublishConfigRequest) error {
+	// the names, the format and the canonical payload are set on a copy, the request of the caller is left as is
+	publishConfigRequest = proto.Clone(publishConfigRequest).(*configproto.PublishConfigRequest)
+
+	var err error
+	publishConfigRequest.AppGroupName, publishConfigRequest.ConfigName, err = resolveNames(ctx, publishConfigRequest.AppGroupName, publishConfigRequest.ConfigName, "PublishConfig")
+	if err != nil {
+		return err
+	}
+
+	if client.grpcClient == nil {
+		return errors.New("[client.PublishConfig] grpc server can not be connected")
+	}
+
+	publishConfigRequest.Private, publishConfigRequest.Format, err = client.publishPayload(publishConfigRequest.Private, publishConfigRequest.Format, "PublishConfig")
+	if err != nil {
+		return err
+	}
+
+	// reject the payload before sending when it does not match the registered schema
+	if err := client.grpcClient.schemas.Validate(publishConfigRequest.AppGroupName, publishConfigRequest.ConfigName,
+		publishConfigRequest.Private, publishConfigRequest.Format); err != nil {
+		return err
+	}
+
+	return client.grpcClient.publishConfig(ctx, publishConfigRequest)
+}
+
+// publishPayload normalizes the format and validates the private object, or canonicalizes it
+// with the CanonicalPublish option, the way PublishConfig sends it
+func (client *ConfigClient) publishPayload(private, format, caller string) (string, string, error) {
+	// reject a payload the server could not parse before sending it
+	format, err := utils.NormalizeFormat(format)
+	if err != nil {
+		return "", "", errors.New("[client." + caller + "] " + err.Error())
+	}
+	if client.grpcClient.options.CanonicalPublish {
+		if private, err = utils.CanonicalizeConfig(private, format); err != nil {
+			return "", "", err
+		}
+	} else if err := utils.ValidateConfig(private, format); err != nil {
+		return "", "", err
+	}
+	return private, format, nil
+}
+
+// PublishObject encodes v, a struct or a map, in the format and publishes it as the private object of the config.
+// Struct fields are named as in Unmarshal, see decode.Marshal.
+func (client *ConfigClient) PublishObject(ctx context.Context, appGroupName, configName string, v interface{}, format string, tag, description string) error {
+	values, err := decode.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	private, err := utils.EncodeConfig(values, format)
+	if err != nil {
+		return err
+	}
+
+	return client.PublishConfigContext(ctx, &configproto.PublishConfigRequest{
+		AppGroupName: appGroupName,
+		ConfigName:   configName,
+		Private:      private,
+		Format:       format,
+		TagName:      tag,
+		Description:  description,
+	})
+}
+
+// PatchConfig applies set, delete and merge operations on dotted keys of the private object
+// and publishes the edited source, see patch.Apply. The publish fails with *VersionConflictError
+// when the config changed since it was read.
+func (client *ConfigClient) PatchConfig(appGroupName, configName string, ops []patch.Operation) error {
+	return client.PatchConfigContext(context.Background(), appGroupName, configName, ops)
+}
+
+// PatchConfigContext is PatchConfig with a context which bounds the rpcs
+func (client *ConfigClient) PatchConfigContext(ctx context.Context, appGroupName, configName string, ops []patch.Operation) error {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "PatchConfig")
+	if err != nil {
+		return err
+	}
+
+	serviceConfig, err := client.fetchConfig(ctx, appGroupName, configName, "PatchConfig")
+	if err != nil {
+		return err
+	}
+
+	private, err := patch.Apply(serviceConfig.Private, serviceConfig.Format, ops)
+	if err != nil {
+		return err
+	}
+
+	return client.PublishConfigContext(ctx, &configproto.PublishConfigRequest{
+		AppGroupName:    appGroupName,
+		ConfigName:      configName,
+		Private:         private,
+		Format:          serviceConfig.Format,
+		ExpectedVersion: serviceConfig.Version,
+	})
+}
+
+// RegisterSchema sets the json schema the private object of the config must match,
+// it is checked before PublishConfig and on every received update
+func (client *ConfigClient) RegisterSchema(appGroupName, configName, schema string) error {
+	if client.grpcClient == nil {
+		return errors.New("[client.RegisterSchema] grpc server can not be connected")
+	}
+	return client.grpcClient.schemas.Register(appGroupName, configName, schema)
+}
+
+// UnregisterSchema removes the json schema of the config
+func (client *ConfigClient) UnregisterSchema(appGroupName, configName string) {
+	if client.grpcClient != nil {
+		client.grpcClient.schemas.Unregister(appGroupName, configName)
+	}
+}
+
+// ListenConfig listens the changes of the config until the returned subscription is stopped,
+// listening the same config twice shares the streams of the first call
+func (client *ConfigClient) ListenConfig(param config.ListenConfigParam) (*Subscription, error) {
+	return client.ListenConfigContext(context.Background(), param)
+}
+
+// ListenConfigContext is ListenConfig with a context which bounds the setup of the listen streams,
+// the streams keep running after the context is done until the subscription is stopped
+func (client *ConfigClient) ListenConfigContext(ctx context.Context, param config.ListenConfigParam) (*Subscription, error) {
+	var err error
+	param.AppGroupName, param.ConfigName, err = resolveNames(ctx, param.AppGroupName, param.ConfigName, "ListenConfig")
+	if err != nil {
+		return nil, err
+	}
+
+	serviceKey := utils.GetServiceConfigKey(param.AppGroupName, param.ConfigName)
+
+	if client.grpcClient == nil {
+		return nil, errors.New("[client.ListenConfig] grpc server can not be connected")
+	}
+
+	if client.serviceConfig[serviceKey] == nil {
+		client.serviceConfig[serviceKey] = &configproto.Config{}
+	}
+
+	subscription, err := client.grpcClient.listenConfig(ctx, client.serviceConfig[serviceKey], &param)
+	if err != nil {
+		return nil, err
+	}
+
+	// export every key at subscribe time, not only the keys of the next change
+	client.grpcClient.exportEnv(client.serviceConfig[serviceKey])
+	return subscription, nil
+}
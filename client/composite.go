@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/decode"
+	"ecm-sdk-go/types"
+)
+
+// Composite stacks several configs of one app group into one key space,
+// later config names override earlier ones, e.g. "base", "region", "instance"
+type Composite struct {
+	client       *ConfigClient
+	appGroupName string
+	configNames  []string
+	precedence   []string
+
+	mutex         sync.RWMutex
+	layers        []*types.EffectiveConfig
+	subscriptions []*Subscription
+}
+
+// CompositeChangeEvent is the change of the effective value of one key of a Composite
+type CompositeChangeEvent struct {
+	Key      string
+	OldValue string
+	NewValue string
+	Kind     config.ChangeKind
+	// ConfigName and Object tell where the new value comes from, the old one for deleted keys
+	ConfigName string
+	Object     string
+}
+
+// NewComposite creates a composite of the config names ordered from the lowest to the highest layer,
+// precedence orders the objects inside every layer as in GetEffectiveConfig
+func (client *ConfigClient) NewComposite(appGroupName string, configNames []string, precedence ...string) *Composite {
+	return &Composite{
+		client:       client,
+		appGroupName: appGroupName,
+		configNames:  append([]string{}, configNames...),
+		precedence:   precedence,
+		layers:       make([]*types.EffectiveConfig, len(configNames)),
+	}
+}
+
+// Load fetches every layer from the server
+func (composite *Composite) Load(ctx context.Context) error {
+	if len(composite.configNames) == 0 {
+		return errors.New("[client.Composite] no config name")
+	}
+
+	layers := make([]*types.EffectiveConfig, len(composite.configNames))
+	for i, configName := range composite.configNames {
+		layer, err := composite.client.GetEffectiveConfigContext(ctx, composite.appGroupName, configName, composite.precedence...)
+		if err != nil {
+			return err
+		}
+		layers[i] = layer
+	}
+
+	composite.mutex.Lock()
+	composite.layers = layers
+	composite.mutex.Unlock()
+	return nil
+}
+
+// Get returns the effective value of the key with the config name and the object it comes from
+func (composite *Composite) Get(key string) (interface{}, string, string, bool) {
+	composite.mutex.RLock()
+	defer composite.mutex.RUnlock()
+
+	for i := len(composite.layers) - 1; i >= 0; i-- {
+		if composite.layers[i] == nil {
+			continue
+		}
+		if value, object, ok := composite.layers[i].Get(key); ok {
+			return value, composite.configNames[i], object, true
+		}
+	}
+	return nil, "", "", false
+}
+
+// Values returns a copy of the merged key space
+func (composite *Composite) Values() map[string]interface{} {
+	composite.mutex.RLock()
+	defer composite.mutex.RUnlock()
+
+	values := map[string]interface{}{}
+	for key, value := range composite.merge() {
+		values[key] = value.value
+	}
+	return values
+}
+
+// View returns a typed view over the merged key space
+func (composite *Composite) View() *types.KeyValueView {
+	return types.NewKeyValueView(composite.Values())
+}
+
+// Unmarshal decodes the merged key space into out, see decode.Unmarshal
+func (composite *Composite) Unmarshal(out interface{}) error {
+	return decode.Unmarshal(composite.Values(), out)
+}
+
+// Listen loads the layers and listens all of them, onChange receives the keys whose
+// effective value changed after any layer changed. Keys overridden by a higher layer are not reported.
+func (composite *Composite) Listen(ctx context.Context, onChange func(events []CompositeChangeEvent)) error {
+	if err := composite.Load(ctx); err != nil {
+		return err
+	}
+
+	subscriptions := []*Subscription{}
+	for i, configName := range composite.configNames {
+		index := i
+		subscription, err := composite.client.ListenConfigContext(ctx, config.ListenConfigParam{
+			AppGroupName: composite.appGroupName,
+			ConfigName:   configName,
+			OnChangeSet: func(changeSet config.ChangeSet) {
+				composite.updateLayer(index, changeSet.Config, onChange)
+			},
+		})
+		if err != nil {
+			for _, subscription := range subscriptions {
+				subscription.Stop()
+			}
+			return err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	composite.mutex.Lock()
+	composite.subscriptions = append(composite.subscriptions, subscriptions...)
+	composite.mutex.Unlock()
+	return nil
+}
+
+// Stop ends the listening of all layers
+func (composite *Composite) Stop() {
+	composite.mutex.Lock()
+	subscriptions := composite.subscriptions
+	composite.subscriptions = nil
+	composite.mutex.Unlock()
+
+	for _, subscription := range subscriptions {
+		subscription.Stop()
+	}
+}
+
+func (composite *Composite) updateLayer(index int, keyValueConfig *types.KeyValueConfig, onChange func(events []CompositeChangeEvent)) {
+	layer, err := types.NewEffectiveConfig(keyValueConfig, composite.precedence...)
+	if err != nil {
+		return
+	}
+
+	composite.mutex.Lock()
+	before := composite.merge()
+	composite.layers[index] = layer
+	after := composite.merge()
+	composite.mutex.Unlock()
+
+	events := diffComposite(before, after)
+	if len(events) > 0 && onChange != nil {
+		onChange(events)
+	}
+}
+
+type compositeValue struct {
+	value      interface{}
+	configName string
+	object     string
+}
+
+// merge resolves every key from the highest layer down, the caller holds the mutex
+func (composite *Composite) merge() map[string]compositeValue {
+	merged := map[string]compositeValue{}
+	for i := len(composite.layers) - 1; i >= 0; i-- {
+		layer := composite.layers[i]
+		if layer == nil {
+			continue
+		}
+		for key, value := range layer.Values {
+			if _, ok := merged[key]; ok {
+				continue
+			}
+			merged[key] = compositeValue{value: value, configName: composite.configNames[i], object: layer.Sources[key]}
+		}
+	}
+	return merged
+}
+
+func diffComposite(before, after map[string]compositeValue) []CompositeChangeEvent {
+	events := []CompositeChangeEvent{}
+	for key, value := range after {
+		newValue := fmt.Sprintf("%v", value.value)
+		event := CompositeChangeEvent{
+			Key:        key,
+			NewValue:   newValue,
+			Kind:       config.ChangeAdded,
+			ConfigName: value.configName,
+			Object:     value.object,
+		}
+		if old, ok := before[key]; ok {
+			event.OldValue = fmt.Sprintf("%v", old.value)
+			if event.OldValue == newValue {
+				continue
+			}
+			event.Kind = config.ChangeModified
+		}
+		events = append(events, event)
+	}
+
+	for key, old := range before {
+		if _, ok := after[key]; !ok {
+			events = append(events, CompositeChangeEvent{
+				Key:        key,
+				OldValue:   fmt.Sprintf("%v", old.value),
+				Kind:       config.ChangeDeleted,
+				ConfigName: old.configName,
+				Object:     old.object,
+			})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+
+	configproto "ecm-sdk-go/proto"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// Degraded reports whether the client serves the cached configs because the server has not answered yet,
+// only a client started with ClientConfig.LoadCacheAtStart is degraded
+func (client *ConfigClient) Degraded() bool {
+	return client.grpcClient != nil && client.grpcClient.isDegraded()
+}
+
+func (c *GrpcClient) isDegraded() bool {
+	return atomic.LoadInt32(&c.degraded) == 1
+}
+
+// startDegraded serves the cache until the server answers
+func (c *GrpcClient) startDegraded() {
+	atomic.StoreInt32(&c.degraded, 1)
+	c.logger.Printf("[client.grpc_client] serving the cache until %s answers", c.EcmServerAddr)
+	go c.awaitServer()
+}
+
+// awaitServer waits until the connection is ready, then leaves the degraded mode, opens the streams
+// of the configs listened meanwhile and refreshes them so their listeners see what changed since the cache
+func (c *GrpcClient) awaitServer() {
+	ctx := c.background
+	conn, _, _ := c.connection()
+	if conn == nil {
+		if err := c.reconnect(ctx); err != nil {
+			return
+		}
+		conn, _, _ = c.connection()
+	}
+
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+
+	// listenConfig registers a new watcher before it checks the mode, a watcher registered after the switch opens its own streams
+	atomic.StoreInt32(&c.degraded, 0)
+	c.logger.Printf("[client.grpc_client] %s answered, leaving the degraded mode", c.EcmServerAddr)
+
+	for _, watcher := range c.activeWatchers() {
+		c.streamClientMutex.RLock()
+		opened := watcher.listenConfigClient != nil
+		c.streamClientMutex.RUnlock()
+		if !opened {
+			if err := c.openStreams(watcher); err != nil {
+				c.logger.Printf("[client.awaitServer] open streams failed: " + err.Error())
+			}
+		}
+		c.refreshWatcher(ctx, watcher)
+	}
+}
+
+// loadFromCache fills the empty service config of a new watcher from the cache
+func (c *GrpcClient) loadFromCache(ctx context.Context, watcher *configWatcher) {
+	data, err := c.cache.Read(ctx, watcher.appGroupName, watcher.configName)
+	if err != nil {
+		c.logger.Printf("[client.listenConfig] read %s from cache failed: %s", watcher.serviceKey, err.Error())
+		return
+	}
+
+	c.serviceConfigMutex.Lock()
+	defer c.serviceConfigMutex.Unlock()
+	if watcher.serviceConfig.Version != "" || watcher.serviceConfig.PublicVersion != "" {
+		return
+	}
+	if change := c.updateServiceConfig(watcher.serviceConfig, data); change.err != nil {
+		c.logger.Printf("[client.listenConfig] resolve %s failed: %s", watcher.serviceKey, change.err.Error())
+	}
+}
+
+// refreshWatcher gets the config of the watcher from the server and notifies its listeners of the changes
+func (c *GrpcClient) refreshWatcher(ctx context.Context, watcher *configWatcher) {
+	c.serviceConfigMutex.RLock()
+	configVersion := &configproto.ConfigVersion{
+		Version:       watcher.serviceConfig.Version,
+		AppGroupName:  watcher.appGroupName,
+		ConfigName:    watcher.configName,
+		PublicVersion: watcher.serviceConfig.PublicVersion,
+	}
+	c.serviceConfigMutex.RUnlock()
+
+	var data *configproto.Config
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = c.rpcClient().GetConfig(ctx, configVersion)
+		return err
+	})
+	if err != nil {
+		c.logger.Printf("[client.awaitServer] refresh %s failed: %s", watcher.serviceKey, err.Error())
+		return
+	}
+	c.applyConfig(watcher, data)
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// VersionConflictError is returned by PublishConfig when PublishConfigRequest.ExpectedVersion
+// is set and the config was published by someone else since that version
+type VersionConflictError struct {
+	AppGroupName    string
+	ConfigName      string
+	ExpectedVersion string
+	// ActualVersion is empty when the server rejected the publish without telling the version
+	ActualVersion string
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.ActualVersion == "" {
+		return fmt.Sprintf("[client.PublishConfig] version conflict on %s/%s: expected version '%s' is not the latest",
+			e.AppGroupName, e.ConfigName, e.ExpectedVersion)
+	}
+	return fmt.Sprintf("[client.PublishConfig] version conflict on %s/%s: expected version '%s', server has '%s'",
+		e.AppGroupName, e.ConfigName, e.ExpectedVersion, e.ActualVersion)
+}
+
+// StaleConfigError is returned instead of a cached config fetched longer ago than the max staleness,
+// see config.WithMaxStaleness
+type StaleConfigError struct {
+	AppGroupName string
+	ConfigName   string
+	// FetchedAt is zero when the cache does not tell the fetch time
+	FetchedAt    time.Time
+	MaxStaleness time.Duration
+}
+
+func (e *StaleConfigError) Error() string {
+	if e.FetchedAt.IsZero() {
+		return fmt.Sprintf("[client.getConfig] the server can not be reached and the fetch time of the cached %s/%s is unknown",
+			e.AppGroupName, e.ConfigName)
+	}
+	return fmt.Sprintf("[client.getConfig] the server can not be reached and the cached %s/%s was fetched %s ago, more than %s",
+		e.AppGroupName, e.ConfigName, time.Since(e.FetchedAt).Truncate(time.Millisecond), e.MaxStaleness)
+}
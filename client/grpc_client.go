@@ -1,729 +1,854 @@
-package client
-
-import (
-	"context"
-	"errors"
-	"fmt"
-	"log"
-	"math/rand"
-	"os"
-	"reflect"
-	"sync"
-	"time"
-
-	"ecm-sdk-go/cache"
-	"ecm-sdk-go/config"
-	"ecm-sdk-go/constants"
-	configproto "ecm-sdk-go/proto"
-	"ecm-sdk-go/utils"
-	util "ecm-sdk-go/utils"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-)
-
-type GrpcClient struct {
-	EcmServerAddr      string
-	config             config.ClientConfig
-	client             configproto.ConfigServiceClient
-	ctx                context.Context
-	listenConfigClient map[string]configproto.ConfigService_ListenConfigClient
-	putConfigClient    map[string]configproto.ConfigService_PutConfigClient
-	conn               *grpc.ClientConn
-	serviceConfigMutex sync.RWMutex
-	streamClientMutex  sync.RWMutex
-	cancel             context.CancelFunc
-	deleteChan         chan int
-	listenRecvChan     chan int
-	listenSendChan     chan int
-	putRecvChan        chan int
-	putSendChan        chan int
-	chanCount          int
-}
-
-func newGrpcClient(clientConfig config.ClientConfig) (*GrpcClient, error) {
-
-	EcmServerAddr := clientConfig.EcmServerAddr
-	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithInsecure())
-	// use custom credential
-	opts = append(opts, grpc.WithPerRPCCredentials(new(customCredential)))
-	conn, err := grpc.Dial(EcmServerAddr, opts...)
-	if err != nil {
-		return nil, err
-	}
-
-	c := configproto.NewConfigServiceClient(conn)
-	ctx, cancel := context.WithCancel(context.Background())
-
-	return &GrpcClient{
-		EcmServerAddr:      EcmServerAddr,
-		config:             clientConfig,
-		conn:               conn,
-		client:             c,
-		ctx:                ctx,
-		cancel:             cancel,
-		listenConfigClient: make(map[string]configproto.ConfigService_ListenConfigClient),
-		putConfigClient:    make(map[string]configproto.ConfigService_PutConfigClient),
-		chanCount:          0,
-	}, nil
-
-}
-
-func (c *GrpcClient) deleteGrpcClient() {
-
-	// stop send and recv thread
-	if c.listenRecvChan != nil {
-		c.listenRecvChan <- 1
-	}
-
-	if c.listenSendChan != nil {
-		c.listenSendChan <- 1
-	}
-
-	if c.putSendChan != nil {
-		c.putSendChan <- 1
-	}
-
-	if c.putRecvChan != nil {
-		c.putRecvChan <- 1
-	}
-
-	if c.deleteChan != nil {
-		for i := 0; i < c.chanCount; i++ {
-			select {
-			case <-c.deleteChan:
-				log.Printf("[client.grpc_client] stop signal")
-			}
-		}
-	}
-
-	c.closeStreamClient()
-}
-
-func (c *GrpcClient) reconnect() {
-	// stop grpc client before reconnect
-	c.closeStreamClient()
-
-	interval := time.Second
-	for {
-		log.Printf("[client.grpc_client] Reconnect")
-		var opts []grpc.DialOption
-		opts = append(opts, grpc.WithInsecure())
-		// use custom credential
-		opts = append(opts, grpc.WithPerRPCCredentials(new(customCredential)))
-		conn, err := grpc.Dial(c.EcmServerAddr, opts...)
-		if err != nil {
-			time.Sleep(interval + time.Duration(rand.Intn(1000))*time.Millisecond)
-			interval = computeInterval(interval)
-			continue
-		}
-
-		client := configproto.NewConfigServiceClient(conn)
-
-		ctx, cancel := context.WithCancel(context.Background())
-
-		isFailed := false
-		c.streamClientMutex.Lock()
-		for key := range c.listenConfigClient {
-			if _, ok := c.listenConfigClient[key]; ok {
-				if c.listenConfigClient[key], err = client.ListenConfig(ctx); err != nil {
-					isFailed = true
-					break
-				}
-			}
-		}
-		c.streamClientMutex.Unlock()
-		if isFailed {
-			time.Sleep(interval + time.Duration(rand.Intn(1000))*time.Millisecond)
-			interval = computeInterval(interval)
-			continue
-		}
-
-		c.streamClientMutex.Lock()
-		for key := range c.putConfigClient {
-			if _, ok := c.putConfigClient[key]; ok {
-				if c.putConfigClient[key], err = client.PutConfig(ctx); err != nil {
-					isFailed = true
-					break
-				}
-				appGroupName, configName, err := utils.GetAppGroupNameAndConfigName(key)
-				if err == nil {
-					putConfigRequest := &configproto.PutConfigRequest{
-						AppGroupName: appGroupName,
-						ConfigName:   configName,
-					}
-					err := c.putConfigClient[key].Send(putConfigRequest)
-					if err != nil {
-						errStatus, _ := status.FromError(err)
-						if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-							log.Printf("[client.reconnect] put send thread failed: " + err.Error())
-							continue
-						}
-						log.Printf("[client.reconnect] put send thread failed: " + err.Error())
-						isFailed = true
-						break
-					}
-				}
-			}
-		}
-		c.streamClientMutex.Unlock()
-		if isFailed {
-			time.Sleep(interval + time.Duration(rand.Intn(1000))*time.Millisecond)
-			interval = computeInterval(interval)
-			continue
-		}
-
-		c.client = client
-		c.ctx = ctx
-		c.conn = conn
-		c.cancel = cancel
-		log.Printf("[client.grpc_client] Connected")
-		break
-	}
-}
-
-func (c *GrpcClient) closeStreamClient() {
-	if c == nil {
-		return
-	}
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
-
-	c.streamClientMutex.Lock()
-	for key := range c.listenConfigClient {
-		if _, ok := c.listenConfigClient[key]; ok {
-			c.listenConfigClient[key] = nil
-		}
-	}
-	for key := range c.putConfigClient {
-		if _, ok := c.putConfigClient[key]; ok {
-			c.putConfigClient[key] = nil
-		}
-	}
-	c.streamClientMutex.Unlock()
-
-	c.cancel()
-
-}
-
-func (c *GrpcClient) getConfig(appGroupName, configName string, serviceConfig *configproto.Config) error {
-
-	// send rpc
-	c.serviceConfigMutex.RLock()
-	data, err := c.client.GetConfig(c.ctx, &configproto.ConfigVersion{
-		Version:       serviceConfig.Version,
-		AppGroupName:  appGroupName,
-		ConfigName:    configName,
-		PublicVersion: serviceConfig.PublicVersion,
-	})
-	c.serviceConfigMutex.RUnlock()
-
-	if err != nil {
-		errStatus, _ := status.FromError(err)
-		if errStatus.Code() == codes.NotFound {
-			// write empty string to cache file
-			cache.WriteConfigToCache(c.config.CachePath, appGroupName, configName, &configproto.Config{})
-			log.Printf("[client.getConfig] " + errStatus.Message())
-			return err
-		} else if errStatus.Code() == codes.Internal || errStatus.Code() == codes.Unavailable {
-			// get config from cache
-			data, err = cache.ReadConfigFromCache(c.config.CachePath, appGroupName, configName)
-			if err != nil {
-				log.Printf("[ERROR] get config from cache  error:%s ", err.Error())
-				return errors.New("read config from both server and cache fail")
-			}
-		} else {
-			log.Printf("[client.getConfig] " + err.Error())
-			return err
-		}
-	}
-
-	if data != nil && !reflect.DeepEqual(data, &configproto.Config{}) {
-		c.serviceConfigMutex.Lock()
-
-		// update service config and set env
-		if err := c.updateServiceConfig(serviceConfig, data, nil); err != nil {
-			return err
-		}
-
-		// write config to cache file
-		cache.WriteConfigToCache(c.config.CachePath, appGroupName, configName, serviceConfig)
-		c.serviceConfigMutex.Unlock()
-	}
-
-	return nil
-}
-
-func (c *GrpcClient) publishConfig(publishConfigRequest *configproto.PublishConfigRequest) error {
-
-	response, err := c.client.PublishConfig(c.ctx, publishConfigRequest)
-	if err != nil {
-		errStatus, _ := status.FromError(err)
-		if errStatus.Code() == codes.Unavailable {
-			// retry send rpc
-			c.reconnect()
-			response, err = c.client.PublishConfig(c.ctx, publishConfigRequest)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	}
-
-	if response.Result != constants.GrpcResponseSuccess {
-		return errors.New("Publish config failed: " + response.Result)
-	}
-
-	return nil
-}
-
-func (c *GrpcClient) listenConfig(serviceConfig *configproto.Config, param *config.ListenConfigParam) {
-	// initial delete channel
-	if c.deleteChan == nil {
-		c.deleteChan = make(chan int)
-	}
-
-	// initial receive channel
-	if c.listenRecvChan == nil {
-		c.listenRecvChan = make(chan int)
-	}
-	c.chanCount++
-
-	// create listen config client
-	listenClientKey := utils.GetServiceConfigKeyAddRandom(param.AppGroupName, param.ConfigName)
-	c.streamClientMutex.Lock()
-	listenConfigClient, err := c.client.ListenConfig(c.ctx)
-	if err != nil {
-		log.Printf(err.Error())
-		c.streamClientMutex.Unlock()
-		return
-
-	}
-	c.listenConfigClient[listenClientKey] = listenConfigClient
-	c.streamClientMutex.Unlock()
-
-	listenStopCh := make(chan struct{})
-	go func() {
-		for {
-			select {
-			case <-c.listenRecvChan:
-				log.Printf("[client.listenConfig] listen receive thread receive graceful shutdown signal")
-				c.deleteChan <- c.chanCount
-				return
-			default:
-				c.streamClientMutex.RLock()
-				listenConfigClient := c.listenConfigClient[listenClientKey]
-				c.streamClientMutex.RUnlock()
-
-				if listenConfigClient == nil {
-					time.Sleep(time.Second)
-					continue
-				}
-				data, err := listenConfigClient.Recv()
-				if err != nil {
-					log.Printf("[client.listenConfig] listen receive thread failed: " + err.Error())
-					errStatus, _ := status.FromError(err)
-					if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-						close(listenStopCh)
-						return
-					}
-					if errStatus.Code() == codes.Internal {
-						c.reconnect()
-					}
-					time.Sleep(time.Second)
-					continue
-				}
-
-				c.serviceConfigMutex.Lock()
-				// update service config and set env
-				if err := c.updateServiceConfig(serviceConfig, data, param); err != nil {
-					c.serviceConfigMutex.Unlock()
-					continue
-				}
-
-				// write config to cache file
-				if param != nil {
-					cache.WriteConfigToCache(c.config.CachePath, param.AppGroupName, param.ConfigName, serviceConfig)
-				}
-				c.serviceConfigMutex.Unlock()
-			}
-		}
-	}()
-
-	// initial send channel
-	if c.listenSendChan == nil {
-		c.listenSendChan = make(chan int)
-	}
-	c.chanCount++
-	go func() {
-		c.streamClientMutex.RLock()
-		listenConfigClient := c.listenConfigClient[listenClientKey]
-		c.streamClientMutex.RUnlock()
-
-		c.serviceConfigMutex.RLock()
-		err := listenConfigClient.Send(&configproto.ConfigVersion{
-			Version:       serviceConfig.Version,
-			AppGroupName:  param.AppGroupName,
-			ConfigName:    param.ConfigName,
-			PublicVersion: serviceConfig.PublicVersion,
-		})
-		c.serviceConfigMutex.RUnlock()
-		if err != nil {
-			errStatus, _ := status.FromError(err)
-			if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-				log.Printf("[client.listenConfig] listen send thread failed: " + err.Error())
-				return
-			}
-			log.Printf("[client.listenConfig] listen send thread failed: " + err.Error())
-			c.reconnect()
-		}
-
-		t1 := time.NewTimer(time.Duration(c.config.ListenInterval) * time.Second)
-		for {
-			select {
-			case <-c.listenSendChan:
-				log.Printf("[client.listenConfig] listen send thread receive graceful shutdown signal")
-				c.deleteChan <- c.chanCount
-				return
-			case <-listenStopCh:
-				log.Printf("[client.listenConfig] listen send thread quit")
-				return
-			case <-t1.C:
-				c.streamClientMutex.RLock()
-				listenConfigClient := c.listenConfigClient[listenClientKey]
-				c.streamClientMutex.RUnlock()
-				if listenConfigClient == nil {
-					t1.Reset(time.Duration(c.config.ListenInterval) * time.Second)
-					continue
-				}
-
-				c.serviceConfigMutex.RLock()
-				err := listenConfigClient.Send(&configproto.ConfigVersion{
-					Version:       serviceConfig.Version,
-					AppGroupName:  param.AppGroupName,
-					ConfigName:    param.ConfigName,
-					PublicVersion: serviceConfig.PublicVersion,
-				})
-				c.serviceConfigMutex.RUnlock()
-				if err != nil {
-					errStatus, _ := status.FromError(err)
-					if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-						log.Printf("[client.listenConfig] listen send thread failed: " + err.Error())
-						return
-					}
-					log.Printf("[client.listenConfig] listen send thread failed: " + err.Error())
-					c.reconnect()
-				}
-				t1.Reset(time.Duration(c.config.ListenInterval) * time.Second)
-			}
-		}
-	}()
-
-	if c.putSendChan == nil {
-		c.putSendChan = make(chan int)
-	}
-	c.chanCount++
-
-	putStopCh := make(chan struct{})
-
-	// create put config client
-	c.streamClientMutex.Lock()
-	putConfigClient, err := c.client.PutConfig(c.ctx)
-	if err != nil {
-		log.Printf(err.Error())
-	}
-	c.putConfigClient[listenClientKey] = putConfigClient
-	c.streamClientMutex.Unlock()
-
-	go func() {
-		putConfigRequest := &configproto.PutConfigRequest{
-			AppGroupName: param.AppGroupName,
-			ConfigName:   param.ConfigName,
-		}
-		c.streamClientMutex.RLock()
-		putConfigClient := c.putConfigClient[listenClientKey]
-		c.streamClientMutex.RUnlock()
-
-		err := putConfigClient.Send(putConfigRequest)
-		if err != nil {
-			errStatus, _ := status.FromError(err)
-			if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-				log.Printf("[client.listenConfig] put send thread failed: " + err.Error())
-				return
-			}
-			log.Printf("[client.listenConfig] put send thread failed: " + err.Error())
-			c.reconnect()
-		}
-
-		// send heartbeat package
-		t1 := time.NewTimer(time.Duration(constants.HeartBeatInterval) * time.Second)
-		for {
-			select {
-			case <-c.putSendChan:
-				log.Printf("[client.listenConfig] put send thread receive graceful shutdown signal")
-				c.deleteChan <- c.chanCount
-				return
-			case <-putStopCh:
-				log.Printf("[client.listenConfig] put send thread quit")
-				return
-			case <-t1.C:
-				c.streamClientMutex.RLock()
-				putConfigClient := c.putConfigClient[listenClientKey]
-				c.streamClientMutex.RUnlock()
-
-				if putConfigClient == nil {
-					t1.Reset(time.Duration(constants.HeartBeatInterval) * time.Second)
-					continue
-				}
-				putConfigRequest := &configproto.PutConfigRequest{
-					AppGroupName:     param.AppGroupName,
-					ConfigName:       param.ConfigName,
-					HeartBeatPackage: constants.HeartBeatPackage,
-				}
-				err := putConfigClient.Send(putConfigRequest)
-				if err != nil {
-					errStatus, _ := status.FromError(err)
-					if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-						log.Printf("[client.listenConfig] put send thread failed: " + err.Error())
-						return
-					}
-					log.Printf("[client.listenconfig] put send thread failed: " + err.Error())
-				}
-				t1.Reset(time.Duration(constants.HeartBeatInterval) * time.Second)
-			}
-		}
-	}()
-
-	// initial send channel
-	if c.putRecvChan == nil {
-		c.putRecvChan = make(chan int)
-	}
-	c.chanCount++
-	go func() {
-		for {
-			select {
-			case <-c.putRecvChan:
-				log.Printf("[client.listenConfig] put receive thread receive graceful shutdown signal")
-				c.deleteChan <- c.chanCount
-				return
-			default:
-				c.streamClientMutex.RLock()
-				putConfigClient := c.putConfigClient[listenClientKey]
-				c.streamClientMutex.RUnlock()
-
-				if putConfigClient == nil {
-					time.Sleep(time.Second)
-					continue
-				}
-
-				data, err := putConfigClient.Recv()
-				if err != nil {
-					log.Printf("[client.listenConfig] put receive thread failed: " + err.Error())
-					errStatus, _ := status.FromError(err)
-					if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
-						close(putStopCh)
-						return
-					}
-					if errStatus.Code() == codes.Internal {
-						c.reconnect()
-					}
-					time.Sleep(time.Second)
-					continue
-				}
-				if data == nil {
-					log.Printf("[client.listenconfig] receive data from put config request is empty")
-					continue
-				}
-
-				// delete message of config server
-				deleteMessageRequest := &configproto.UpdateConfigMessage{
-					Key:   data.UpdateConfigMessage.Key,
-					Value: data.UpdateConfigMessage.Value,
-				}
-
-				response, err := c.client.DeleteMessage(c.ctx, deleteMessageRequest)
-				if err != nil || response.Result != constants.GrpcResponseSuccess {
-					// retry
-					c.client.DeleteMessage(c.ctx, deleteMessageRequest)
-				}
-
-				c.serviceConfigMutex.Lock()
-				// update service config
-
-				if err := c.updateServiceConfig(serviceConfig, data.Config, param); err != nil {
-					c.serviceConfigMutex.Unlock()
-					continue
-				}
-
-				// write config to cache file
-				if param != nil {
-					cache.WriteConfigToCache(c.config.CachePath, param.AppGroupName, param.ConfigName, serviceConfig)
-				}
-				c.serviceConfigMutex.Unlock()
-			}
-		}
-	}()
-
-}
-
-func computeInterval(t time.Duration) time.Duration {
-	return t * 2
-}
-
-func (c *GrpcClient) updateServiceConfig(serviceConfig, changedConfig *configproto.Config, param *config.ListenConfigParam) error {
-	// update public
-	// check changed and added keys
-	if changedConfig.PublicVersion != "" {
-		changedPublic := make(map[string]interface{})
-		if changedConfig.Public != "" {
-			var err error
-			changedPublic, err = util.ParseConfigToMap(changedConfig.Public, changedConfig.PublicFormat)
-			if err != nil {
-				return err
-			}
-		}
-
-		public := make(map[string]interface{})
-		if serviceConfig.Public != "" {
-			var err error
-			public, err = util.ParseConfigToMap(serviceConfig.Public, serviceConfig.PublicFormat)
-			if err != nil {
-				return err
-			}
-		}
-		for key, value := range changedPublic {
-			if public[key] != changedPublic[key] {
-				// call onChange function
-				if param != nil && param.OnChange != nil {
-					param.OnChange(constants.PublicObjectName, key, fmt.Sprintf("%v", value))
-				}
-
-				// set public env
-				if c.config.UpdateEnvWhenChanged {
-					os.Setenv(key, fmt.Sprintf("%v", value))
-				}
-			}
-		}
-
-		// check deleted keys
-		for key := range public {
-			if _, ok := changedPublic[key]; !ok {
-				if param != nil && param.OnChange != nil {
-					param.OnChange(constants.PublicObjectName, key, "")
-				}
-				// set public env
-				if c.config.UpdateEnvWhenChanged {
-					os.Setenv(key, "")
-				}
-			}
-		}
-		serviceConfig.Public = changedConfig.Public
-		serviceConfig.PublicVersion = changedConfig.PublicVersion
-		serviceConfig.PublicFormat = changedConfig.PublicFormat
-	}
-
-	// update private
-	if changedConfig.Version != "" {
-		changedPrivate := make(map[string]interface{})
-		if changedConfig.Private != "" {
-			var err error
-			changedPrivate, err = util.ParseConfigToMap(changedConfig.Private, changedConfig.Format)
-			if err != nil {
-				return err
-			}
-		}
-
-		private := make(map[string]interface{})
-		if serviceConfig.Private != "" {
-			var err error
-			private, err = util.ParseConfigToMap(serviceConfig.Private, serviceConfig.Format)
-			if err != nil {
-				return err
-			}
-		}
-
-		for key, value := range changedPrivate {
-			if private[key] != changedPrivate[key] {
-				// call onChange function
-				if param != nil && param.OnChange != nil {
-					param.OnChange(constants.PrivateObjectName, key, fmt.Sprintf("%v", value))
-				}
-
-				// set private env
-				if c.config.UpdateEnvWhenChanged {
-					os.Setenv(key, fmt.Sprintf("%v", value))
-				}
-			}
-		}
-
-		// check deleted keys
-		for key := range private {
-			if _, ok := changedPrivate[key]; !ok {
-				if param != nil && param.OnChange != nil {
-					param.OnChange(constants.PrivateObjectName, key, "")
-				}
-				if c.config.UpdateEnvWhenChanged {
-					os.Setenv(key, "")
-				}
-			}
-		}
-		serviceConfig.Private = changedConfig.Private
-		serviceConfig.Version = changedConfig.Version
-		serviceConfig.Format = changedConfig.Format
-	}
-
-	// update services
-	if changedConfig.PublicVersion != "" {
-		changedServices := make(map[string]interface{})
-		if changedConfig.Services != "" {
-			var err error
-			changedServices, err = util.ParseConfigToMap(changedConfig.Services, "json")
-			if err != nil {
-				return err
-			}
-		}
-
-		services := make(map[string]interface{})
-		if serviceConfig.Services != "" {
-			var err error
-			services, err = util.ParseConfigToMap(serviceConfig.Services, "json")
-			if err != nil {
-				return err
-			}
-		}
-
-		for key, value := range changedServices {
-			if services[key] != changedServices[key] {
-				// call onChange function
-				if param != nil && param.OnChange != nil {
-					param.OnChange(constants.ServicesObjectName, key, fmt.Sprintf("%v", value))
-				}
-
-				// set private env
-				if c.config.UpdateEnvWhenChanged {
-					os.Setenv(key, fmt.Sprintf("%v", value))
-				}
-			}
-		}
-
-		// check deleted keys
-		for key := range services {
-			if _, ok := changedServices[key]; !ok {
-				if param != nil && param.OnChange != nil {
-					param.OnChange(constants.ServicesObjectName, key, "")
-				}
-				if c.config.UpdateEnvWhenChanged {
-					os.Setenv(key, "")
-				}
-			}
-		}
-		serviceConfig.Services = changedConfig.Services
-		serviceConfig.PublicVersion = changedConfig.PublicVersion
-	}
-	return nil
-}
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"ecm-sdk-go/cache"
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/interpolate"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/schema"
+	"ecm-sdk-go/secret"
+	"ecm-sdk-go/types"
+	util "ecm-sdk-go/utils"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type GrpcClient struct {
+	EcmServerAddr      string
+	config             config.ClientConfig
+	serviceConfigMutex sync.RWMutex
+	streamClientMutex  sync.RWMutex
+
+	// client, ctx, conn and cancel are replaced by reconnect, guarded by connMutex
+	client    configproto.ConfigServiceClient
+	ctx       context.Context
+	conn      *grpc.ClientConn
+	cancel    context.CancelFunc
+	connMutex sync.RWMutex
+
+	// reconnecting is closed when the running reconnect ends, nil when none runs
+	reconnecting   chan struct{}
+	reconnectMutex sync.Mutex
+
+	watchers     map[string]*configWatcher
+	watcherMutex sync.Mutex
+	options      *config.Options
+	logger       config.Logger
+	cache        *cache.StoreCache
+	schemas      *schema.Registry
+	envExporter  *config.EnvExporter
+
+	// fetches holds where the last read of every config came from
+	fetches    map[string]types.FetchInfo
+	fetchMutex sync.Mutex
+
+	// degraded is 1 while a client started from the cache has not reached the server
+	degraded       int32
+	background     context.Context
+	stopBackground context.CancelFunc
+}
+
+func newGrpcClient(clientConfig config.ClientConfig, options *config.Options) (*GrpcClient, error) {
+
+	store := options.Store
+	if store == nil {
+		cipher := options.CacheCipher
+		if cipher == nil {
+			var err error
+			if cipher, err = cache.NewCipherFromEnv(); err != nil {
+				return nil, err
+			}
+		}
+		fileStore := &cache.FileStore{Dir: clientConfig.CachePath, Cipher: cipher}
+
+		// the files written with the previous key, or in plaintext, are rewritten with the current one before the first read
+		oldCipher := options.CacheOldCipher
+		if oldCipher == nil {
+			var err error
+			if oldCipher, err = cache.NewOldCipherFromEnv(); err != nil {
+				return nil, err
+			}
+		}
+		if oldCipher != nil || cipher != nil {
+			if err := fileStore.RekeyFrom(oldCipher); err != nil {
+				return nil, err
+			}
+		}
+		store = fileStore
+	}
+	configCache := cache.NewStoreCache(store)
+	configCache.HistorySize = options.HistorySize
+
+	// the values are decrypted with the key file of the environment unless a decrypter is set
+	if options.Decrypter == nil {
+		if keyFile := os.Getenv(constants.SecretKeyFileEnvVar); keyFile != "" {
+			decrypter, err := secret.NewAESGCMFromKeyFile(keyFile)
+			if err != nil {
+				return nil, err
+			}
+			options.Decrypter = decrypter
+		}
+	}
+
+	envExporter := options.EnvExporter
+	if envExporter == nil && clientConfig.UpdateEnvWhenChanged {
+		// keep the raw key names the client set before the exporter existed
+		var err error
+		if envExporter, err = config.NewEnvExporter(config.EnvExportParam{Naming: config.RawKey}); err != nil {
+			return nil, err
+		}
+	}
+
+	EcmServerAddr := clientConfig.EcmServerAddr
+	conn, err := grpc.Dial(EcmServerAddr, dialOptions(options)...)
+	if err != nil && !clientConfig.LoadCacheAtStart {
+		return nil, err
+	}
+
+	var c configproto.ConfigServiceClient
+	if conn != nil {
+		c = configproto.NewConfigServiceClient(conn)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	background, stopBackground := context.WithCancel(context.Background())
+
+	grpcClient := &GrpcClient{
+		EcmServerAddr:  EcmServerAddr,
+		config:         clientConfig,
+		conn:           conn,
+		client:         c,
+		ctx:            ctx,
+		cancel:         cancel,
+		watchers:       make(map[string]*configWatcher),
+		options:        options,
+		logger:         options.Logger,
+		cache:          configCache,
+		schemas:        schema.NewRegistry(),
+		envExporter:    envExporter,
+		fetches:        make(map[string]types.FetchInfo),
+		background:     background,
+		stopBackground: stopBackground,
+	}
+	if clientConfig.LoadCacheAtStart {
+		if err != nil {
+			grpcClient.logger.Printf("[client.grpc_client] dial %s failed: %s", EcmServerAddr, err.Error())
+		}
+		grpcClient.startDegraded()
+	}
+	return grpcClient, nil
+
+}
+
+func dialOptions(options *config.Options) []grpc.DialOption {
+	var opts []grpc.DialOption
+	if options.TransportCredentials != nil {
+		opts = append(opts, grpc.WithTransportCredentials(options.TransportCredentials))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if options.Credentials != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(options.Credentials))
+	} else {
+		// use custom credential
+		opts = append(opts, grpc.WithPerRPCCredentials(new(customCredential)))
+	}
+	if len(options.UnaryInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(options.UnaryInterceptors...))
+	}
+	if len(options.StreamInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainStreamInterceptor(options.StreamInterceptors...))
+	}
+	return append(opts, options.DialOptions...)
+}
+
+// invoke runs a unary rpc with the rpc timeout and retries it on Unavailable following the retry policy
+func (c *GrpcClient) invoke(ctx context.Context, rpc func(ctx context.Context) error) error {
+	if c.rpcClient() == nil {
+		return status.Error(codes.Unavailable, "the server is not connected")
+	}
+	policy := c.options.RetryPolicy
+	backoff := policy.InitialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		rpcCtx, cancel := ctx, context.CancelFunc(func() {})
+		if _, ok := ctx.Deadline(); !ok && c.options.RPCTimeout > 0 {
+			rpcCtx, cancel = context.WithTimeout(ctx, c.options.RPCTimeout)
+		}
+		err = rpc(rpcCtx)
+		cancel()
+
+		if err == nil || attempt >= policy.MaxAttempts || status.Code(err) != codes.Unavailable {
+			return err
+		}
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = c.nextBackoff(backoff)
+	}
+}
+
+func (c *GrpcClient) nextBackoff(backoff time.Duration) time.Duration {
+	backoff = computeInterval(backoff)
+	if maxBackoff := c.options.RetryPolicy.MaxBackoff; maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func (c *GrpcClient) deleteGrpcClient() {
+	c.stopBackground()
+
+	// stop send and recv thread of every listened config
+	c.watcherMutex.Lock()
+	watchers := c.watchers
+	c.watchers = make(map[string]*configWatcher)
+	c.watcherMutex.Unlock()
+
+	for _, watcher := range watchers {
+		<-watcher.ready
+		c.terminateWatcher(watcher)
+		<-watcher.done
+		c.logger.Printf("[client.grpc_client] stop signal")
+	}
+
+	c.closeStreamClient()
+}
+
+// reconnect replaces the connection and the streams of every watcher, ctx only bounds the wait of the caller.
+// The reconnect runs on the background context of the client and is shared by concurrent callers,
+// the current connection is kept until the new one has opened all streams.
+func (c *GrpcClient) reconnect(ctx context.Context) error {
+	c.reconnectMutex.Lock()
+	done := c.reconnecting
+	if done == nil {
+		done = make(chan struct{})
+		c.reconnecting = done
+		go c.runReconnect(done)
+	}
+	c.reconnectMutex.Unlock()
+
+	select {
+	case <-done:
+		return c.background.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runReconnect dials the server until it succeeds or the client is deleted
+func (c *GrpcClient) runReconnect(done chan struct{}) {
+	defer func() {
+		c.reconnectMutex.Lock()
+		c.reconnecting = nil
+		c.reconnectMutex.Unlock()
+		close(done)
+	}()
+
+	ctx := c.background
+	interval := c.options.RetryPolicy.InitialBackoff
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for ctx.Err() == nil {
+		c.logger.Printf("[client.grpc_client] Reconnect")
+		if err := c.dialAndSwap(); err == nil {
+			c.logger.Printf("[client.grpc_client] Connected")
+			return
+		}
+		if err := sleepContext(ctx, interval+time.Duration(rand.Intn(1000))*time.Millisecond); err != nil {
+			return
+		}
+		interval = c.nextBackoff(interval)
+	}
+}
+
+// dialAndSwap dials a new connection and opens the streams of every watcher on it,
+// then replaces the current connection and streams and closes them
+func (c *GrpcClient) dialAndSwap() error {
+	conn, err := grpc.Dial(c.EcmServerAddr, dialOptions(c.options)...)
+	if err != nil {
+		return err
+	}
+	client := configproto.NewConfigServiceClient(conn)
+	clientCtx, cancel := context.WithCancel(context.Background())
+
+	watchers := c.activeWatchers()
+	opened := make([]*watcherStreams, 0, len(watchers))
+	for _, watcher := range watchers {
+		streams, err := c.newStreams(clientCtx, client, watcher)
+		if err != nil {
+			c.logger.Printf("[client.reconnect] open streams failed: " + err.Error())
+			cancel()
+			conn.Close()
+			return err
+		}
+		opened = append(opened, streams)
+	}
+
+	c.connMutex.Lock()
+	oldConn, oldCancel := c.conn, c.cancel
+	c.client = client
+	c.ctx = clientCtx
+	c.conn = conn
+	c.cancel = cancel
+	c.connMutex.Unlock()
+
+	for i, watcher := range watchers {
+		c.setStreams(watcher, opened[i])
+	}
+	if oldCancel != nil {
+		oldCancel()
+	}
+	if oldConn != nil {
+		oldConn.Close()
+	}
+	return nil
+}
+
+// rpcClient returns the client of the current connection, nil before a client started from the cache connects
+func (c *GrpcClient) rpcClient() configproto.ConfigServiceClient {
+	c.connMutex.RLock()
+	defer c.connMutex.RUnlock()
+	return c.client
+}
+
+// connection returns the current connection and the context its streams are opened with
+func (c *GrpcClient) connection() (*grpc.ClientConn, configproto.ConfigServiceClient, context.Context) {
+	c.connMutex.RLock()
+	defer c.connMutex.RUnlock()
+	return c.conn, c.client, c.ctx
+}
+
+func (c *GrpcClient) closeStreamClient() {
+	if c == nil {
+		return
+	}
+	c.connMutex.Lock()
+	conn, cancel := c.conn, c.cancel
+	c.conn = nil
+	c.connMutex.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+
+	for _, watcher := range c.activeWatchers() {
+		c.closeStreams(watcher)
+	}
+
+	cancel()
+
+}
+
+func (c *GrpcClient) getConfig(ctx context.Context, appGroupName, configName string, serviceConfig *configproto.Config) error {
+
+	// a degraded client answers from the cache without waiting for the server
+	if c.isDegraded() {
+		if data, fetchedAt, err := c.readCache(ctx, appGroupName, configName); err == nil && c.checkStaleness(appGroupName, configName, fetchedAt) == nil {
+			c.serviceConfigMutex.Lock()
+			change := c.updateServiceConfig(serviceConfig, data)
+			c.serviceConfigMutex.Unlock()
+			c.recordFetch(appGroupName, configName, types.SourceCache, fetchedAt)
+			c.notifyFetched(appGroupName, configName, serviceConfig, change)
+			return nil
+		}
+	}
+
+	// send rpc
+	c.serviceConfigMutex.RLock()
+	configVersion := &configproto.ConfigVersion{
+		Version:       serviceConfig.Version,
+		AppGroupName:  appGroupName,
+		ConfigName:    configName,
+		PublicVersion: serviceConfig.PublicVersion,
+	}
+	c.serviceConfigMutex.RUnlock()
+
+	var data *configproto.Config
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = c.rpcClient().GetConfig(ctx, configVersion)
+		return err
+	})
+
+	source, fetchedAt := types.SourceServer, time.Now()
+	if err != nil {
+		errStatus, _ := status.FromError(err)
+		if errStatus.Code() == codes.NotFound {
+			// write empty string to cache file
+			c.cache.Write(appGroupName, configName, &configproto.Config{})
+			c.logger.Printf("[client.getConfig] " + errStatus.Message())
+			return err
+		} else if errStatus.Code() == codes.Internal || errStatus.Code() == codes.Unavailable ||
+			(errStatus.Code() == codes.DeadlineExceeded && ctx.Err() == nil) {
+			// get config from cache
+			data, fetchedAt, err = c.readCache(ctx, appGroupName, configName)
+			if err != nil && err == ctx.Err() {
+				return err
+			}
+			if err != nil {
+				c.logger.Printf("[ERROR] get config from cache  error:%s ", err.Error())
+				return errors.New("read config from both server and cache fail")
+			}
+			if err := c.checkStaleness(appGroupName, configName, fetchedAt); err != nil {
+				c.logger.Printf(err.Error())
+				return err
+			}
+			source = types.SourceCache
+		} else {
+			c.logger.Printf("[client.getConfig] " + err.Error())
+			return err
+		}
+	}
+
+	if data != nil && !reflect.DeepEqual(data, &configproto.Config{}) {
+		// keep the last good config when the received one does not match the schema
+		if data.Version != "" {
+			if err := c.schemas.Validate(appGroupName, configName, data.Private, data.Format); err != nil {
+				c.logger.Printf("[client.getConfig] " + err.Error())
+				c.serviceConfigMutex.RLock()
+				hasGoodConfig := serviceConfig.Version != ""
+				c.serviceConfigMutex.RUnlock()
+				if hasGoodConfig {
+					return nil
+				}
+				return err
+			}
+		}
+
+		c.serviceConfigMutex.Lock()
+
+		// update service config and set env, the raw config is kept when its key values can not be resolved
+		change := c.updateServiceConfig(serviceConfig, data)
+
+		// write config to cache file
+		if source == types.SourceServer {
+			c.cache.Write(appGroupName, configName, serviceConfig)
+		}
+		c.serviceConfigMutex.Unlock()
+		c.recordFetch(appGroupName, configName, source, fetchedAt)
+		c.notifyFetched(appGroupName, configName, serviceConfig, change)
+		return nil
+	} else if source == types.SourceServer && (serviceConfig.Version != "" || serviceConfig.PublicVersion != "") {
+		// the server has nothing newer, the cached config is fresh again
+		c.serviceConfigMutex.RLock()
+		c.cache.Write(appGroupName, configName, serviceConfig)
+		c.serviceConfigMutex.RUnlock()
+	}
+
+	c.recordFetch(appGroupName, configName, source, fetchedAt)
+	return nil
+}
+
+// peekConfig reads the config like getConfig without applying it, the service config, the cache,
+// the env and the listeners are left as they are
+func (c *GrpcClient) peekConfig(ctx context.Context, appGroupName, configName string) (*configproto.Config, types.FetchInfo, error) {
+	if c.isDegraded() {
+		if data, fetchedAt, err := c.readCache(ctx, appGroupName, configName); err == nil && c.checkStaleness(appGroupName, configName, fetchedAt) == nil {
+			return data, types.FetchInfo{Source: types.SourceCache, FetchedAt: fetchedAt}, nil
+		}
+	}
+
+	// without versions the server answers with the whole config
+	configVersion := &configproto.ConfigVersion{AppGroupName: appGroupName, ConfigName: configName}
+	var data *configproto.Config
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = c.rpcClient().GetConfig(ctx, configVersion)
+		return err
+	})
+	if err == nil {
+		if data == nil {
+			data = &configproto.Config{}
+		}
+		return data, types.FetchInfo{Source: types.SourceServer, FetchedAt: time.Now()}, nil
+	}
+
+	code := status.Code(err)
+	if code != codes.Internal && code != codes.Unavailable && !(code == codes.DeadlineExceeded && ctx.Err() == nil) {
+		return nil, types.FetchInfo{}, err
+	}
+	data, fetchedAt, err := c.readCache(ctx, appGroupName, configName)
+	if err != nil && err == ctx.Err() {
+		return nil, types.FetchInfo{}, err
+	}
+	if err != nil {
+		return nil, types.FetchInfo{}, errors.New("read config from both server and cache fail")
+	}
+	if err := c.checkStaleness(appGroupName, configName, fetchedAt); err != nil {
+		return nil, types.FetchInfo{}, err
+	}
+	return data, types.FetchInfo{Source: types.SourceCache, FetchedAt: fetchedAt}, nil
+}
+
+// notifyFetched notifies the listeners of the config of a change applied by getConfig, the watcher shares
+// the service config with GetConfig so the listeners would miss the change otherwise. It is called without
+// the config lock, the listeners may call the client.
+func (c *GrpcClient) notifyFetched(appGroupName, configName string, serviceConfig *configproto.Config, change *configChange) {
+	var listeners []*listener
+	c.watcherMutex.Lock()
+	if watcher, ok := c.watchers[util.GetServiceConfigKey(appGroupName, configName)]; ok && watcher.serviceConfig == serviceConfig {
+		listeners = append(listeners, watcher.listeners...)
+	}
+	c.watcherMutex.Unlock()
+
+	if change.err != nil {
+		c.logger.Printf("[client.getConfig] resolve %s/%s failed: %s", appGroupName, configName, change.err.Error())
+		for _, l := range listeners {
+			if l.param.OnError != nil {
+				l.param.OnError(change.err)
+			}
+		}
+		return
+	}
+	c.notifyListeners(listeners, change)
+}
+
+// readCache reads the cached config and the time it was fetched, zero when the cache does not tell it
+func (c *GrpcClient) readCache(ctx context.Context, appGroupName, configName string) (*configproto.Config, time.Time, error) {
+	entry, err := c.cache.ReadEntry(ctx, appGroupName, configName)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return entry.Config, entry.FetchedAt, nil
+}
+
+// checkStaleness refuses a cached config fetched longer ago than the max staleness
+func (c *GrpcClient) checkStaleness(appGroupName, configName string, fetchedAt time.Time) error {
+	maxStaleness := c.options.MaxStaleness
+	if maxStaleness <= 0 {
+		return nil
+	}
+	if fetchedAt.IsZero() || time.Since(fetchedAt) > maxStaleness {
+		return &StaleConfigError{
+			AppGroupName: appGroupName,
+			ConfigName:   configName,
+			FetchedAt:    fetchedAt,
+			MaxStaleness: maxStaleness,
+		}
+	}
+	return nil
+}
+
+func (c *GrpcClient) recordFetch(appGroupName, configName, source string, fetchedAt time.Time) {
+	c.fetchMutex.Lock()
+	defer c.fetchMutex.Unlock()
+	c.fetches[util.GetServiceConfigKey(appGroupName, configName)] = types.FetchInfo{Source: source, FetchedAt: fetchedAt}
+}
+
+func (c *GrpcClient) fetchInfo(appGroupName, configName string) types.FetchInfo {
+	c.fetchMutex.Lock()
+	defer c.fetchMutex.Unlock()
+	return c.fetches[util.GetServiceConfigKey(appGroupName, configName)]
+}
+
+func (c *GrpcClient) publishConfig(ctx context.Context, publishConfigRequest *configproto.PublishConfigRequest) error {
+
+	// servers which do not know ExpectedVersion ignore it, check it on the client first
+	if publishConfigRequest.ExpectedVersion != "" {
+		if err := c.checkVersion(ctx, publishConfigRequest); err != nil {
+			return err
+		}
+	}
+
+	var response *configproto.Response
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = c.rpcClient().PublishConfig(ctx, publishConfigRequest)
+		return err
+	})
+	if err != nil {
+		errStatus, _ := status.FromError(err)
+		if errStatus.Code() == codes.Unavailable {
+			// retry send rpc
+			if err := c.reconnect(ctx); err != nil {
+				return err
+			}
+			err = c.invoke(ctx, func(ctx context.Context) error {
+				var err error
+				response, err = c.rpcClient().PublishConfig(ctx, publishConfigRequest)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		} else if errStatus.Code() == codes.Aborted && publishConfigRequest.ExpectedVersion != "" {
+			// the server saw another publish after the check
+			return &VersionConflictError{
+				AppGroupName:    publishConfigRequest.AppGroupName,
+				ConfigName:      publishConfigRequest.ConfigName,
+				ExpectedVersion: publishConfigRequest.ExpectedVersion,
+			}
+		} else {
+			return err
+		}
+	}
+
+	if response.Result != constants.GrpcResponseSuccess {
+		return errors.New("Publish config failed: " + response.Result)
+	}
+
+	return nil
+}
+
+// checkVersion fetches the current version of the config and compares it with the expected one
+func (c *GrpcClient) checkVersion(ctx context.Context, publishConfigRequest *configproto.PublishConfigRequest) error {
+	// an empty version asks the server for the whole config
+	configVersion := &configproto.ConfigVersion{
+		AppGroupName: publishConfigRequest.AppGroupName,
+		ConfigName:   publishConfigRequest.ConfigName,
+	}
+
+	var data *configproto.Config
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = c.rpcClient().GetConfig(ctx, configVersion)
+		return err
+	})
+	if err != nil {
+		if errStatus, _ := status.FromError(err); errStatus.Code() != codes.NotFound {
+			return err
+		}
+		data = &configproto.Config{}
+	}
+
+	if data.Version != publishConfigRequest.ExpectedVersion {
+		return &VersionConflictError{
+			AppGroupName:    publishConfigRequest.AppGroupName,
+			ConfigName:      publishConfigRequest.ConfigName,
+			ExpectedVersion: publishConfigRequest.ExpectedVersion,
+			ActualVersion:   data.Version,
+		}
+	}
+	return nil
+}
+
+func computeInterval(t time.Duration) time.Duration {
+	return t * 2
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// keyValueConfig flattens the config and applies the client side transformations of the options
+func (c *GrpcClient) keyValueConfig(serviceConfig *configproto.Config) (*types.KeyValueConfig, error) {
+	keyValueConfig, err := util.ParseKeyValueConfig(serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// decrypt before interpolation so references see the plain values,
+	// the decrypted values never reach the cache which stores the raw config
+	if c.options.Decrypter != nil {
+		if keyValueConfig.Public, err = secret.DecryptValues(keyValueConfig.Public, c.options.Decrypter); err != nil {
+			return nil, err
+		}
+		if keyValueConfig.Private, err = secret.DecryptValues(keyValueConfig.Private, c.options.Decrypter); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.options.Interpolate {
+		if keyValueConfig.Public, err = interpolate.Resolve(keyValueConfig.Public, nil); err != nil {
+			return nil, err
+		}
+		if keyValueConfig.Private, err = interpolate.Resolve(keyValueConfig.Private, keyValueConfig.Public); err != nil {
+			return nil, err
+		}
+	}
+
+	return keyValueConfig, nil
+}
+
+// configChange is the result of an update of the service config, the listeners are notified of it.
+// err tells the key values of the new config could not be parsed, decrypted or interpolated,
+// the raw config is stored anyway and only the key value reads fail
+type configChange struct {
+	events    []config.ChangeEvent
+	changeSet config.ChangeSet
+	err       error
+}
+
+// updateServiceConfig applies the changed objects to the service config and returns what changed,
+// it must be called with serviceConfigMutex held
+func (c *GrpcClient) updateServiceConfig(serviceConfig, changedConfig *configproto.Config) *configChange {
+	// apply the changed objects to a copy of the service config
+	nextConfig := *serviceConfig
+	if changedConfig.PublicVersion != "" {
+		nextConfig.Public = changedConfig.Public
+		nextConfig.PublicVersion = changedConfig.PublicVersion
+		nextConfig.PublicFormat = changedConfig.PublicFormat
+		nextConfig.Services = changedConfig.Services
+	}
+	if changedConfig.Version != "" {
+		nextConfig.Private = changedConfig.Private
+		nextConfig.Version = changedConfig.Version
+		nextConfig.Format = changedConfig.Format
+	}
+
+	// a current config which could not be resolved is diffed as empty, its keys were never readable
+	current, err := c.keyValueConfig(serviceConfig)
+	if err != nil {
+		current = &types.KeyValueConfig{}
+	}
+	next, err := c.keyValueConfig(&nextConfig)
+	if err != nil {
+		c.setServiceConfig(serviceConfig, &nextConfig)
+		return &configChange{err: err}
+	}
+
+	// check changed, added and deleted keys of public, private and services,
+	// resolved values are compared so keys referencing a changed key are reported too
+	events := []config.ChangeEvent{}
+	events = append(events, diffObject(constants.PublicObjectName, nextConfig.PublicVersion, current.Public, next.Public)...)
+	events = append(events, diffObject(constants.PrivateObjectName, nextConfig.Version, current.Private, next.Private)...)
+	events = append(events, diffObject(constants.ServicesObjectName, nextConfig.PublicVersion, current.Services, next.Services)...)
+
+	changeSet := config.ChangeSet{
+		OldVersion:       serviceConfig.Version,
+		NewVersion:       nextConfig.Version,
+		OldPublicVersion: serviceConfig.PublicVersion,
+		NewPublicVersion: nextConfig.PublicVersion,
+		Config:           next,
+	}
+
+	// update service config
+	c.setServiceConfig(serviceConfig, &nextConfig)
+
+	// set env
+	if c.envExporter != nil {
+		c.envExporter.Apply(events, next)
+	}
+	return &configChange{events: events, changeSet: changeSet}
+}
+
+func (c *GrpcClient) setServiceConfig(serviceConfig, nextConfig *configproto.Config) {
+	serviceConfig.Public = nextConfig.Public
+	serviceConfig.PublicVersion = nextConfig.PublicVersion
+	serviceConfig.PublicFormat = nextConfig.PublicFormat
+	serviceConfig.Services = nextConfig.Services
+	serviceConfig.Private = nextConfig.Private
+	serviceConfig.Version = nextConfig.Version
+	serviceConfig.Format = nextConfig.Format
+}
+
+// notifyListeners calls the listeners with the change, it must not be called with serviceConfigMutex held
+// because the listeners may call the client
+func (c *GrpcClient) notifyListeners(listeners []*listener, change *configChange) {
+	changeSet := change.changeSet
+	for _, l := range listeners {
+		// drop the keys the listener is not interested in before dispatching
+		listenerEvents := l.filter.Filter(change.events)
+		if len(listenerEvents) == 0 {
+			continue
+		}
+
+		// call onChange function
+		for _, event := range listenerEvents {
+			if l.param.OnChange != nil {
+				l.param.OnChange(event.Object, event.Key, event.NewValue)
+			}
+			if l.param.OnEvent != nil {
+				l.param.OnEvent(event)
+			}
+		}
+
+		// deliver all changes of the version transition at once
+		if l.param.OnChangeSet != nil {
+			listenerChangeSet := config.NewChangeSet(listenerEvents)
+			listenerChangeSet.OldVersion = changeSet.OldVersion
+			listenerChangeSet.NewVersion = changeSet.NewVersion
+			listenerChangeSet.OldPublicVersion = changeSet.OldPublicVersion
+			listenerChangeSet.NewPublicVersion = changeSet.NewPublicVersion
+			listenerChangeSet.Config = changeSet.Config
+			l.param.OnChangeSet(listenerChangeSet)
+		}
+	}
+}
+
+// exportEnv exports every key of the service config, the updates only export the changed keys
+func (c *GrpcClient) exportEnv(serviceConfig *configproto.Config) {
+	if c.envExporter == nil {
+		return
+	}
+
+	c.serviceConfigMutex.RLock()
+	defer c.serviceConfigMutex.RUnlock()
+	if serviceConfig.Version == "" && serviceConfig.PublicVersion == "" {
+		return
+	}
+	keyValueConfig, err := c.keyValueConfig(serviceConfig)
+	if err != nil {
+		c.logger.Printf("[client.exportEnv] " + err.Error())
+		return
+	}
+	c.envExporter.ExportAll(keyValueConfig)
+}
+
+// diffObject compares the flattened keys of the current and the changed values of one object
+func diffObject(object, version string, current, changed map[string]interface{}) []config.ChangeEvent {
+	events := []config.ChangeEvent{}
+	for _, key := range sortedKeys(changed) {
+		value := changed[key]
+		// flattened values may be slices or maps, e.g. a toml array of tables, which are not comparable
+		oldValue, ok := current[key]
+		if ok && reflect.DeepEqual(oldValue, value) {
+			continue
+		}
+		event := config.ChangeEvent{
+			Object:   object,
+			Key:      key,
+			NewValue: fmt.Sprintf("%v", value),
+			Kind:     config.ChangeAdded,
+			Version:  version,
+		}
+		if ok {
+			event.OldValue = fmt.Sprintf("%v", oldValue)
+			event.Kind = config.ChangeModified
+		}
+		events = append(events, event)
+	}
+
+	// check deleted keys
+	for _, key := range sortedKeys(current) {
+		if _, ok := changed[key]; !ok {
+			events = append(events, config.ChangeEvent{
+				Object:   object,
+				Key:      key,
+				OldValue: fmt.Sprintf("%v", current[key]),
+				Kind:     config.ChangeDeleted,
+				Version:  version,
+			})
+		}
+	}
+	return events
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecm-sdk-go/cache"
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/constants"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/secret"
+)
+
+func TestPublishCancelledDuringReconnectKeepsClient(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+	if _, err := client.ListenConfig(config.ListenConfigParam{AppGroupName: "app", ConfigName: "cfg"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the streams of the watcher can not be opened until the server is back
+	server.server.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request := &configproto.PublishConfigRequest{AppGroupName: "app", ConfigName: "cfg", Private: "a: 2\n", Format: "yaml"}
+	if err := client.PublishConfigContext(ctx, request); err == nil {
+		t.Fatal("publish succeeded while the server is down")
+	}
+
+	server.restart(t)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := client.PublishConfig(request)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("client did not recover: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if private, err := client.GetPrivateConfig("app", "cfg"); err != nil || private != "a: 2\n" {
+		t.Fatalf("got %q, %v", private, err)
+	}
+}
+
+func TestDiffObjectUncomparableValues(t *testing.T) {
+	tables := func(name string) map[string]interface{} {
+		return map[string]interface{}{"servers": []map[string]interface{}{{"name": name}}}
+	}
+
+	if events := diffObject("private", "v2", tables("a"), tables("a")); len(events) != 0 {
+		t.Fatalf("equal values produced events %+v", events)
+	}
+	events := diffObject("private", "v2", tables("a"), tables("b"))
+	if len(events) != 1 || events[0].Key != "servers" || events[0].Kind != config.ChangeModified {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+type failingDecrypter struct{}
+
+func (failingDecrypter) Decrypt(payload string) (string, error) {
+	return "", errors.New("wrong key")
+}
+
+func TestUnresolvedConfigKeepsRawReads(t *testing.T) {
+	tests := map[string]struct {
+		private string
+		option  config.Option
+	}{
+		"interpolation": {"a: ${ENV:ECM_TEST_MISSING}\n", config.WithInterpolation()},
+		"decryption":    {"a: ENC[bm9wZQ==]\n", config.WithDecrypter(failingDecrypter{})},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.set("app", "cfg", test.private, "yaml")
+			client := server.newClient(t, test.option)
+
+			if private, err := client.GetPrivateConfig("app", "cfg"); err != nil || private != test.private {
+				t.Fatalf("GetPrivateConfig = %q, %v", private, err)
+			}
+			if _, err := client.GetKeyValueConfig("app", "cfg"); err == nil {
+				t.Fatal("GetKeyValueConfig resolved the config")
+			}
+		})
+	}
+}
+
+func TestUnresolvedUpdateIsReportedAndLaterUpdatesApply(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t, config.WithInterpolation())
+
+	errs := make(chan error, 1)
+	changes := make(chan string, 1)
+	if _, err := client.ListenConfig(config.ListenConfigParam{
+		AppGroupName: "app",
+		ConfigName:   "cfg",
+		OnChange:     func(object, key, value string) { changes <- value },
+		OnError:      func(err error) { errs <- err },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	server.waitListeners(t, 1)
+
+	server.set("app", "cfg", "a: ${ENV:ECM_TEST_MISSING}\n", "yaml")
+	select {
+	case <-errs:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the unresolved update was not reported")
+	}
+	if private, err := client.GetPrivateConfig("app", "cfg"); err != nil || private != "a: ${ENV:ECM_TEST_MISSING}\n" {
+		t.Fatalf("GetPrivateConfig = %q, %v", private, err)
+	}
+
+	server.set("app", "cfg", "a: 3\n", "yaml")
+	select {
+	case value := <-changes:
+		if value != "3" {
+			t.Fatalf("value = %q", value)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the update after the unresolved one was not applied")
+	}
+}
+
+func TestCanonicalPublishKeepsRequest(t *testing.T) {
+	server := newTestServer(t)
+	client := server.newClient(t, config.WithCanonicalPublish())
+
+	request := &configproto.PublishConfigRequest{AppGroupName: "app", ConfigName: "cfg", Private: "b: 1\na: 2\n", Format: "YML"}
+	if err := client.PublishConfig(request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Private != "b: 1\na: 2\n" || request.Format != "YML" {
+		t.Fatalf("the request was changed to %q, %q", request.Private, request.Format)
+	}
+	if private, err := client.GetPrivateConfig("app", "cfg"); err != nil || private != "a: 2\nb: 1\n" {
+		t.Fatalf("GetPrivateConfig = %q, %v", private, err)
+	}
+}
+
+func TestCacheRekeyOnOpen(t *testing.T) {
+	newCipher := func() cache.Cipher {
+		encoded, _ := secret.GenerateKey()
+		key, _ := secret.ParseKey(encoded)
+		cipher, err := secret.NewAESGCM(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cipher
+	}
+	oldCipher, currentCipher := newCipher(), newCipher()
+	dir := t.TempDir()
+	entry := &cache.Entry{Config: &configproto.Config{Private: "a: 1\n", Format: "yaml", Version: "v1"}}
+	if err := (&cache.FileStore{Dir: dir, Cipher: oldCipher}).Put("my_app", "cfg_one", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer(t)
+	server.newClient(t,
+		config.WithClientConfig(config.ClientConfig{EcmServerAddr: server.addr, CachePath: dir}),
+		config.WithCacheCipher(currentCipher),
+		config.WithCacheRekey(oldCipher),
+	)
+
+	stored, err := (&cache.FileStore{Dir: dir, Cipher: currentCipher}).Get(context.Background(), "my_app", "cfg_one")
+	if err != nil || stored.Config.Version != "v1" {
+		t.Fatalf("Get with the new key = %+v, %v", stored, err)
+	}
+}
+
+func TestStoreKeepsHistoryAndFetchTime(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t, config.WithStore(cache.NewMemoryStore()), config.WithMaxStaleness(time.Hour))
+	if _, err := client.GetPrivateConfig("app", "cfg"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.setDown(true)
+	if private, err := client.GetPrivateConfig("app", "cfg"); err != nil || private != "a: 1\n" {
+		t.Fatalf("GetPrivateConfig from the store = %q, %v", private, err)
+	}
+	if history, err := client.History("app", "cfg"); err != nil || len(history) != 1 {
+		t.Fatalf("History = %v, %v", history, err)
+	}
+}
+
+func TestDecrypterFromEnv(t *testing.T) {
+	encoded, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := ioutil.WriteFile(keyFile, []byte(encoded), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := secret.ParseKey(encoded)
+	encrypter, _ := secret.NewAESGCM(key)
+	wrapped, err := secret.Wrap(encrypter, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(constants.SecretKeyFileEnvVar, keyFile)
+	defer os.Unsetenv(constants.SecretKeyFileEnvVar)
+
+	server := newTestServer(t)
+	server.set("app", "cfg", "password: "+wrapped+"\n", "yaml")
+	client := server.newClient(t)
+
+	keyValueConfig, err := client.GetKeyValueConfig("app", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyValueConfig.Private["password"] != "s3cret" {
+		t.Fatalf("password = %v", keyValueConfig.Private["password"])
+	}
+}
+
+func TestDegradedReadNotifiesListeners(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+
+	changed := make(chan string, 1)
+	subscription, err := client.ListenConfig(config.ListenConfigParam{
+		AppGroupName: "app",
+		ConfigName:   "cfg",
+		OnChange: func(object, key, value string) {
+			if key == "a" && value == "2" {
+				select {
+				case changed <- value:
+				default:
+				}
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Stop()
+	server.waitListeners(t, 1)
+	if _, err := client.GetPrivateConfig("app", "cfg"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a degraded client reads a newer config another client wrote to the cache
+	server.setDown(true)
+	atomic.StoreInt32(&client.grpcClient.degraded, 1)
+	client.grpcClient.cache.Write("app", "cfg", &configproto.Config{Private: "a: 2\n", Format: "yaml", Version: "v9"})
+	if _, err := client.GetPrivateConfig("app", "cfg"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("listener was not notified of the cached config")
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ecm-sdk-go/cache"
+	configproto "ecm-sdk-go/proto"
+)
+
+// RollbackTagPrefix starts the tag of the publish made by Rollback
+const RollbackTagPrefix = "rollback-"
+
+// History returns the versions of the config received by this client and kept in the cache, newest first
+func (client *ConfigClient) History(appGroupName, configName string) ([]cache.HistoryEntry, error) {
+	appGroupName, configName, err := resolveNames(context.Background(), appGroupName, configName, "History")
+	if err != nil {
+		return nil, err
+	}
+	return client.history(appGroupName, configName)
+}
+
+func (client *ConfigClient) history(appGroupName, configName string) ([]cache.HistoryEntry, error) {
+	if client.grpcClient == nil {
+		return nil, errors.New("[client.History] grpc server can not be connected")
+	}
+
+	return client.grpcClient.cache.History(appGroupName, configName)
+}
+
+// Rollback publishes again the private object of a version from History,
+// the publish is tagged "rollback-<version>-<time>"
+func (client *ConfigClient) Rollback(appGroupName, configName, version string) error {
+	return client.RollbackContext(context.Background(), appGroupName, configName, version)
+}
+
+// RollbackContext is Rollback with a context which bounds the rpc
+func (client *ConfigClient) RollbackContext(ctx context.Context, appGroupName, configName, version string) error {
+	appGroupName, configName, err := resolveNames(ctx, appGroupName, configName, "Rollback")
+	if err != nil {
+		return err
+	}
+
+	history, err := client.history(appGroupName, configName)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range history {
+		if entry.Version != version {
+			continue
+		}
+		return client.PublishConfigContext(ctx, &configproto.PublishConfigRequest{
+			AppGroupName: appGroupName,
+			ConfigName:   configName,
+			Private:      entry.Private,
+			Format:       entry.Format,
+			TagName:      RollbackTagPrefix + version + "-" + time.Now().Format("20060102150405"),
+			Description:  fmt.Sprintf("rollback to version %s received at %s", version, entry.ReceivedAt.Format(time.RFC3339)),
+		})
+	}
+	return fmt.Errorf("[client.Rollback] version '%s' is not in the history of %s/%s", version, appGroupName, configName)
+}
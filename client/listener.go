@@ -0,0 +1,543 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/constants"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+	"ecm-sdk-go/utils"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// configWatcher owns the ListenConfig and PutConfig streams and the four threads of one config,
+// every ListenConfig call of the same config adds a listener to the same watcher
+type configWatcher struct {
+	appGroupName  string
+	configName    string
+	serviceKey    string
+	serviceConfig *configproto.Config
+	listeners     []*listener
+	nextID        int
+
+	// ctx is cancelled when the watcher stops
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+	done chan struct{}
+
+	// ready is closed when the streams are opened or the cache is loaded, err tells whether it failed
+	ready chan struct{}
+	err   error
+
+	// applyMutex keeps the updates and their notifications in order, the listeners are called without the config lock
+	applyMutex sync.Mutex
+
+	// streams, guarded by GrpcClient.streamClientMutex
+	listenConfigClient configproto.ConfigService_ListenConfigClient
+	putConfigClient    configproto.ConfigService_PutConfigClient
+	cancelStreams      context.CancelFunc
+}
+
+type listener struct {
+	id     int
+	param  *config.ListenConfigParam
+	filter *config.KeyFilter
+	done   chan struct{}
+}
+
+// Subscription is returned by ListenConfig, Stop ends the listening of this call only
+type Subscription struct {
+	grpcClient *GrpcClient
+	watcher    *configWatcher
+	listener   *listener
+	once       sync.Once
+}
+
+// Stop removes the listener, the streams and threads of the config are closed with the last listener
+func (s *Subscription) Stop() {
+	s.once.Do(func() {
+		s.grpcClient.unsubscribe(s.watcher, s.listener)
+	})
+}
+
+// Done is closed when the subscription is stopped or the server ends the listening
+func (s *Subscription) Done() <-chan struct{} {
+	return s.listener.done
+}
+
+func (c *GrpcClient) listenConfig(ctx context.Context, serviceConfig *configproto.Config, param *config.ListenConfigParam) (*Subscription, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	filter, err := config.NewKeyFilter(param)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceKey := utils.GetServiceConfigKey(param.AppGroupName, param.ConfigName)
+
+	for {
+		// reuse the streams of the config when it is already listened
+		c.watcherMutex.Lock()
+		watcher, ok := c.watchers[serviceKey]
+		if !ok {
+			watcherCtx, stop := context.WithCancel(context.Background())
+			watcher = &configWatcher{
+				appGroupName:  param.AppGroupName,
+				configName:    param.ConfigName,
+				serviceKey:    serviceKey,
+				serviceConfig: serviceConfig,
+				ctx:           watcherCtx,
+				stop:          stop,
+				done:          make(chan struct{}),
+				ready:         make(chan struct{}),
+			}
+			c.watchers[serviceKey] = watcher
+		}
+		c.watcherMutex.Unlock()
+
+		// the streams are opened without the watcher mutex, concurrent calls of the same config wait for them
+		if !ok {
+			if err := c.initWatcher(ctx, watcher); err != nil {
+				return nil, err
+			}
+		} else {
+			select {
+			case <-watcher.ready:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if watcher.err != nil {
+				return nil, watcher.err
+			}
+		}
+
+		c.watcherMutex.Lock()
+		// the watcher ended meanwhile, listen on a new one
+		if c.watchers[serviceKey] != watcher {
+			c.watcherMutex.Unlock()
+			continue
+		}
+		watcher.nextID++
+		l := &listener{
+			id:     watcher.nextID,
+			param:  param,
+			filter: filter,
+			done:   make(chan struct{}),
+		}
+		watcher.listeners = append(watcher.listeners, l)
+		c.watcherMutex.Unlock()
+
+		return &Subscription{grpcClient: c, watcher: watcher, listener: l}, nil
+	}
+}
+
+// initWatcher opens the streams of a new watcher and starts its threads,
+// a degraded client starts from the cache and the streams are opened when the server answers
+func (c *GrpcClient) initWatcher(ctx context.Context, watcher *configWatcher) error {
+	defer close(watcher.ready)
+
+	if c.isDegraded() {
+		c.loadFromCache(ctx, watcher)
+	} else if err := c.openStreams(watcher); err != nil {
+		c.logger.Printf(err.Error())
+		watcher.err = err
+		c.watcherMutex.Lock()
+		if c.watchers[watcher.serviceKey] == watcher {
+			delete(c.watchers, watcher.serviceKey)
+		}
+		c.watcherMutex.Unlock()
+		watcher.stop()
+		close(watcher.done)
+		return err
+	}
+	c.startWatcher(watcher)
+	return nil
+}
+
+// watcherStreams are the listen and put streams of a watcher on one connection
+type watcherStreams struct {
+	listenConfigClient configproto.ConfigService_ListenConfigClient
+	putConfigClient    configproto.ConfigService_PutConfigClient
+	cancel             context.CancelFunc
+}
+
+// openStreams opens the streams of the watcher on the current connection
+func (c *GrpcClient) openStreams(watcher *configWatcher) error {
+	_, client, ctx := c.connection()
+	streams, err := c.newStreams(ctx, client, watcher)
+	if err != nil {
+		return err
+	}
+	c.setStreams(watcher, streams)
+	return nil
+}
+
+// newStreams creates the listen and put streams of the watcher and registers the put stream
+func (c *GrpcClient) newStreams(ctx context.Context, client configproto.ConfigServiceClient, watcher *configWatcher) (*watcherStreams, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	listenConfigClient, err := client.ListenConfig(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	putConfigClient, err := client.PutConfig(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	putConfigRequest := &configproto.PutConfigRequest{
+		AppGroupName: watcher.appGroupName,
+		ConfigName:   watcher.configName,
+	}
+	if err := putConfigClient.Send(putConfigRequest); err != nil {
+		errStatus, _ := status.FromError(err)
+		c.logger.Printf("[client.openStreams] put send thread failed: " + err.Error())
+		if errStatus.Code() != codes.NotFound && errStatus.Code() != codes.PermissionDenied {
+			cancel()
+			return nil, err
+		}
+	}
+
+	return &watcherStreams{listenConfigClient: listenConfigClient, putConfigClient: putConfigClient, cancel: cancel}, nil
+}
+
+// setStreams replaces the streams of the watcher and closes the previous ones
+func (c *GrpcClient) setStreams(watcher *configWatcher, streams *watcherStreams) {
+	c.streamClientMutex.Lock()
+	if watcher.cancelStreams != nil {
+		watcher.cancelStreams()
+	}
+	watcher.listenConfigClient = streams.listenConfigClient
+	watcher.putConfigClient = streams.putConfigClient
+	watcher.cancelStreams = streams.cancel
+	c.streamClientMutex.Unlock()
+}
+
+func (c *GrpcClient) closeStreams(watcher *configWatcher) {
+	c.streamClientMutex.Lock()
+	if watcher.cancelStreams != nil {
+		watcher.cancelStreams()
+		watcher.cancelStreams = nil
+	}
+	watcher.listenConfigClient = nil
+	watcher.putConfigClient = nil
+	c.streamClientMutex.Unlock()
+}
+
+func (c *GrpcClient) activeWatchers() []*configWatcher {
+	c.watcherMutex.Lock()
+	defer c.watcherMutex.Unlock()
+
+	watchers := make([]*configWatcher, 0, len(c.watchers))
+	for _, watcher := range c.watchers {
+		watchers = append(watchers, watcher)
+	}
+	return watchers
+}
+
+func (c *GrpcClient) unsubscribe(watcher *configWatcher, l *listener) {
+	c.watcherMutex.Lock()
+	found := false
+	listeners := []*listener{}
+	for _, item := range watcher.listeners {
+		if item == l {
+			found = true
+			continue
+		}
+		listeners = append(listeners, item)
+	}
+	if !found {
+		c.watcherMutex.Unlock()
+		return
+	}
+	watcher.listeners = listeners
+	last := len(listeners) == 0
+	if last && c.watchers[watcher.serviceKey] == watcher {
+		delete(c.watchers, watcher.serviceKey)
+	}
+	c.watcherMutex.Unlock()
+
+	if !last {
+		close(l.done)
+		return
+	}
+
+	watcher.stop()
+	c.closeStreams(watcher)
+	go func() {
+		<-watcher.done
+		close(l.done)
+	}()
+}
+
+// terminateWatcher stops the watcher and ends all its subscriptions
+func (c *GrpcClient) terminateWatcher(watcher *configWatcher) {
+	c.watcherMutex.Lock()
+	if c.watchers[watcher.serviceKey] == watcher {
+		delete(c.watchers, watcher.serviceKey)
+	}
+	listeners := watcher.listeners
+	watcher.listeners = nil
+	c.watcherMutex.Unlock()
+
+	watcher.stop()
+	c.closeStreams(watcher)
+	go func() {
+		<-watcher.done
+		for _, l := range listeners {
+			close(l.done)
+		}
+	}()
+}
+
+func (c *GrpcClient) listenersOf(watcher *configWatcher) []*listener {
+	c.watcherMutex.Lock()
+	defer c.watcherMutex.Unlock()
+
+	return append([]*listener{}, watcher.listeners...)
+}
+
+func (c *GrpcClient) startWatcher(watcher *configWatcher) {
+	watcher.wg.Add(4)
+	go c.listenReceive(watcher)
+	go c.listenSend(watcher)
+	go c.putSend(watcher)
+	go c.putReceive(watcher)
+
+	go func() {
+		watcher.wg.Wait()
+		close(watcher.done)
+	}()
+}
+
+// applyConfig updates the service config of the watcher, notifies the listeners and writes the cache
+func (c *GrpcClient) applyConfig(watcher *configWatcher, data *configproto.Config) {
+	listeners := c.listenersOf(watcher)
+
+	// refuse the update when it does not match the schema, the last good config stays in effect
+	if data.Version != "" {
+		if err := c.schemas.Validate(watcher.appGroupName, watcher.configName, data.Private, data.Format); err != nil {
+			c.logger.Printf("[client.listenConfig] refuse config version %s: %s", data.Version, err.Error())
+			for _, l := range listeners {
+				if l.param.OnError != nil {
+					l.param.OnError(err)
+				}
+			}
+			return
+		}
+	}
+
+	watcher.applyMutex.Lock()
+	defer watcher.applyMutex.Unlock()
+
+	// update service config and set env
+	c.serviceConfigMutex.Lock()
+	change := c.updateServiceConfig(watcher.serviceConfig, data)
+
+	// write config to cache file
+	c.cache.Write(watcher.appGroupName, watcher.configName, watcher.serviceConfig)
+	c.serviceConfigMutex.Unlock()
+	c.recordFetch(watcher.appGroupName, watcher.configName, types.SourceServer, time.Now())
+
+	// the listeners may call the client, they are notified after the config lock is released
+	if change.err != nil {
+		c.logger.Printf("[client.listenConfig] resolve %s failed: %s", watcher.serviceKey, change.err.Error())
+		for _, l := range listeners {
+			if l.param.OnError != nil {
+				l.param.OnError(change.err)
+			}
+		}
+		return
+	}
+	c.notifyListeners(listeners, change)
+}
+
+func (c *GrpcClient) listenReceive(watcher *configWatcher) {
+	defer watcher.wg.Done()
+
+	for watcher.ctx.Err() == nil {
+		c.streamClientMutex.RLock()
+		listenConfigClient := watcher.listenConfigClient
+		c.streamClientMutex.RUnlock()
+
+		if listenConfigClient == nil {
+			sleepContext(watcher.ctx, time.Second)
+			continue
+		}
+		data, err := listenConfigClient.Recv()
+		if err != nil {
+			if watcher.ctx.Err() != nil {
+				break
+			}
+			c.logger.Printf("[client.listenConfig] listen receive thread failed: " + err.Error())
+			errStatus, _ := status.FromError(err)
+			if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
+				c.terminateWatcher(watcher)
+				return
+			}
+			if errStatus.Code() == codes.Internal {
+				c.reconnect(watcher.ctx)
+			}
+			sleepContext(watcher.ctx, time.Second)
+			continue
+		}
+
+		c.applyConfig(watcher, data)
+	}
+	c.logger.Printf("[client.listenConfig] listen receive thread receive graceful shutdown signal")
+}
+
+func (c *GrpcClient) listenSend(watcher *configWatcher) {
+	defer watcher.wg.Done()
+
+	interval := time.Duration(c.config.ListenInterval) * time.Second
+	t1 := time.NewTimer(0)
+	defer t1.Stop()
+	for {
+		select {
+		case <-watcher.ctx.Done():
+			c.logger.Printf("[client.listenConfig] listen send thread receive graceful shutdown signal")
+			return
+		case <-t1.C:
+			c.streamClientMutex.RLock()
+			listenConfigClient := watcher.listenConfigClient
+			c.streamClientMutex.RUnlock()
+			if listenConfigClient == nil {
+				t1.Reset(interval)
+				continue
+			}
+
+			c.serviceConfigMutex.RLock()
+			configVersion := &configproto.ConfigVersion{
+				Version:       watcher.serviceConfig.Version,
+				AppGroupName:  watcher.appGroupName,
+				ConfigName:    watcher.configName,
+				PublicVersion: watcher.serviceConfig.PublicVersion,
+			}
+			c.serviceConfigMutex.RUnlock()
+
+			if err := listenConfigClient.Send(configVersion); err != nil && watcher.ctx.Err() == nil {
+				errStatus, _ := status.FromError(err)
+				c.logger.Printf("[client.listenConfig] listen send thread failed: " + err.Error())
+				if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
+					c.terminateWatcher(watcher)
+					return
+				}
+				c.reconnect(watcher.ctx)
+			}
+			t1.Reset(interval)
+		}
+	}
+}
+
+func (c *GrpcClient) putSend(watcher *configWatcher) {
+	defer watcher.wg.Done()
+
+	// send heartbeat package
+	interval := time.Duration(constants.HeartBeatInterval) * time.Second
+	t1 := time.NewTimer(interval)
+	defer t1.Stop()
+	for {
+		select {
+		case <-watcher.ctx.Done():
+			c.logger.Printf("[client.listenConfig] put send thread receive graceful shutdown signal")
+			return
+		case <-t1.C:
+			c.streamClientMutex.RLock()
+			putConfigClient := watcher.putConfigClient
+			c.streamClientMutex.RUnlock()
+
+			if putConfigClient == nil {
+				t1.Reset(interval)
+				continue
+			}
+			putConfigRequest := &configproto.PutConfigRequest{
+				AppGroupName:     watcher.appGroupName,
+				ConfigName:       watcher.configName,
+				HeartBeatPackage: constants.HeartBeatPackage,
+			}
+			if err := putConfigClient.Send(putConfigRequest); err != nil && watcher.ctx.Err() == nil {
+				errStatus, _ := status.FromError(err)
+				c.logger.Printf("[client.listenConfig] put send thread failed: " + err.Error())
+				if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
+					c.terminateWatcher(watcher)
+					return
+				}
+			}
+			t1.Reset(interval)
+		}
+	}
+}
+
+func (c *GrpcClient) putReceive(watcher *configWatcher) {
+	defer watcher.wg.Done()
+
+	for watcher.ctx.Err() == nil {
+		c.streamClientMutex.RLock()
+		putConfigClient := watcher.putConfigClient
+		c.streamClientMutex.RUnlock()
+
+		if putConfigClient == nil {
+			sleepContext(watcher.ctx, time.Second)
+			continue
+		}
+
+		data, err := putConfigClient.Recv()
+		if err != nil {
+			if watcher.ctx.Err() != nil {
+				break
+			}
+			c.logger.Printf("[client.listenConfig] put receive thread failed: " + err.Error())
+			errStatus, _ := status.FromError(err)
+			if errStatus.Code() == codes.NotFound || errStatus.Code() == codes.PermissionDenied {
+				c.terminateWatcher(watcher)
+				return
+			}
+			if errStatus.Code() == codes.Internal {
+				c.reconnect(watcher.ctx)
+			}
+			sleepContext(watcher.ctx, time.Second)
+			continue
+		}
+		if data == nil || data.Config == nil {
+			c.logger.Printf("[client.listenconfig] receive data from put config request is empty")
+			continue
+		}
+
+		// delete message of config server
+		if data.UpdateConfigMessage != nil {
+			deleteMessageRequest := &configproto.UpdateConfigMessage{
+				Key:   data.UpdateConfigMessage.Key,
+				Value: data.UpdateConfigMessage.Value,
+			}
+
+			var response *configproto.Response
+			err = c.invoke(watcher.ctx, func(ctx context.Context) error {
+				var err error
+				response, err = c.rpcClient().DeleteMessage(ctx, deleteMessageRequest)
+				return err
+			})
+			if err != nil || response.Result != constants.GrpcResponseSuccess {
+				// retry
+				c.invoke(watcher.ctx, func(ctx context.Context) error {
+					_, err := c.rpcClient().DeleteMessage(ctx, deleteMessageRequest)
+					return err
+				})
+			}
+		}
+
+		c.applyConfig(watcher, data.Config)
+	}
+	c.logger.Printf("[client.listenConfig] put receive thread receive graceful shutdown signal")
+}
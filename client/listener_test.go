@@ -0,0 +1,89 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"ecm-sdk-go/config"
+)
+
+func TestListenerCallingClientDoesNotDeadlock(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+
+	var mutex sync.Mutex
+	called := make(chan string, 1)
+	mutex.Lock()
+	var subscription *Subscription
+	subscription, err := client.ListenConfig(config.ListenConfigParam{
+		AppGroupName: "app",
+		ConfigName:   "cfg",
+		OnChange: func(object, key, value string) {
+			// the listener reads the config and stops its own subscription
+			private, err := client.GetPrivateConfig("app", "cfg")
+			if err != nil {
+				t.Error(err)
+			}
+			mutex.Lock()
+			subscription.Stop()
+			mutex.Unlock()
+			select {
+			case called <- private:
+			default:
+			}
+		},
+	})
+	mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.waitListeners(t, 1)
+	server.set("app", "cfg", "a: 2\n", "yaml")
+
+	select {
+	case private := <-called:
+		if private != "a: 2\n" {
+			t.Fatalf("private = %q", private)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("listener was not called")
+	}
+	select {
+	case <-subscription.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("subscription was not stopped")
+	}
+}
+
+func TestConcurrentListenShareWatcher(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+	if _, err := client.GetPrivateConfig("app", "cfg"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	subscriptions := make([]*Subscription, 8)
+	for i := range subscriptions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			subscription, err := client.ListenConfig(config.ListenConfigParam{AppGroupName: "app", ConfigName: "cfg"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			subscriptions[i] = subscription
+		}(i)
+	}
+	wg.Wait()
+
+	for _, subscription := range subscriptions[1:] {
+		if subscription == nil || subscriptions[0] == nil || subscription.watcher != subscriptions[0].watcher {
+			t.Fatal("the subscriptions of one config do not share the watcher")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+	"ecm-sdk-go/utils"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// KeyChange is the change of one flattened key of the private object
+type KeyChange struct {
+	Key      string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// PublishPreview is what PublishConfig would change, keys are compared on their flattened
+// value so a change of format alone does not show as modified keys
+type PublishPreview struct {
+	AppGroupName   string
+	ConfigName     string
+	CurrentVersion string
+	CurrentFormat  string
+	Format         string
+	// Baseline tells where the current config came from, a preview against types.SourceCache
+	// may miss the changes published since the cache was written
+	Baseline types.FetchInfo
+	Added    []KeyChange
+	Removed  []KeyChange
+	Modified []KeyChange
+	// Diff is the unified line diff of the current and the published source
+	Diff string
+}
+
+// IsEmpty reports whether the publish leaves every key unchanged
+func (preview *PublishPreview) IsEmpty() bool {
+	return len(preview.Added) == 0 && len(preview.Removed) == 0 && len(preview.Modified) == 0
+}
+
+// PreviewPublish compares the request with the current config without publishing it,
+// the current config is read like GetConfig and comes from the cache when the server is unavailable,
+// but it is not applied, cached or notified. The payload is checked against the registered schema.
+func (client *ConfigClient) PreviewPublish(publishConfigRequest *configproto.PublishConfigRequest) (*PublishPreview, error) {
+	return client.PreviewPublishContext(context.Background(), publishConfigRequest)
+}
+
+// PreviewPublishContext is PreviewPublish with a context which bounds the rpc
+func (client *ConfigClient) PreviewPublishContext(ctx context.Context, publishConfigRequest *configproto.PublishConfigRequest) (*PublishPreview, error) {
+	appGroupName, configName, err := resolveNames(ctx, publishConfigRequest.AppGroupName, publishConfigRequest.ConfigName, "PreviewPublish")
+	if err != nil {
+		return nil, err
+	}
+
+	if client.grpcClient == nil {
+		return nil, errors.New("[client.PreviewPublish] grpc server can not be connected")
+	}
+
+	// preview the payload PublishConfig would send
+	private, format, err := client.publishPayload(publishConfigRequest.Private, publishConfigRequest.Format, "PreviewPublish")
+	if err != nil {
+		return nil, err
+	}
+
+	// a payload PublishConfig would reject is not previewed
+	if err := client.grpcClient.schemas.Validate(appGroupName, configName, private, format); err != nil {
+		return nil, err
+	}
+
+	// the current config is read into a copy, a preview changes nothing on the client,
+	// a config which does not exist yet is previewed as empty
+	current, baseline, err := client.grpcClient.peekConfig(ctx, appGroupName, configName)
+	if status.Code(err) == codes.NotFound {
+		current, baseline, err = &configproto.Config{}, types.FetchInfo{Source: types.SourceServer}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	currentValues, err := utils.ParseConfigToMap(current.Private, current.Format)
+	if err != nil {
+		return nil, errors.New("[client.PreviewPublish] flatten current config failed: " + err.Error())
+	}
+	values, err := utils.ParseConfigToMap(private, format)
+	if err != nil {
+		return nil, errors.New("[client.PreviewPublish] flatten published config failed: " + err.Error())
+	}
+
+	preview := &PublishPreview{
+		AppGroupName:   appGroupName,
+		ConfigName:     configName,
+		CurrentVersion: current.Version,
+		CurrentFormat:  current.Format,
+		Format:         format,
+		Baseline:       baseline,
+		Added:          []KeyChange{},
+		Removed:        []KeyChange{},
+		Modified:       []KeyChange{},
+	}
+
+	name := appGroupName + "/" + configName
+	currentName := name
+	if current.Version != "" {
+		currentName += "@" + current.Version
+	}
+	preview.Diff = utils.UnifiedDiff(currentName, name, current.Private, private)
+
+	for _, key := range sortedKeys(values) {
+		oldValue, ok := currentValues[key]
+		switch {
+		case !ok:
+			preview.Added = append(preview.Added, KeyChange{Key: key, NewValue: values[key]})
+		case fmt.Sprintf("%v", oldValue) != fmt.Sprintf("%v", values[key]):
+			preview.Modified = append(preview.Modified, KeyChange{Key: key, OldValue: oldValue, NewValue: values[key]})
+		}
+	}
+	for _, key := range sortedKeys(currentValues) {
+		if _, ok := values[key]; !ok {
+			preview.Removed = append(preview.Removed, KeyChange{Key: key, OldValue: currentValues[key]})
+		}
+	}
+	return preview, nil
+}
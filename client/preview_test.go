@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"ecm-sdk-go/config"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+)
+
+func TestPreviewPublishCanonical(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\nb: 2\n", "yaml")
+	client := server.newClient(t, config.WithCanonicalPublish())
+
+	preview, err := client.PreviewPublish(&configproto.PublishConfigRequest{AppGroupName: "app", ConfigName: "cfg", Private: "b: 2\na: 1\n", Format: "yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.IsEmpty() || preview.Diff != "" {
+		t.Fatalf("preview of the reordered config = %+v", preview)
+	}
+	if preview.Baseline.Source != types.SourceServer {
+		t.Fatalf("baseline = %+v", preview.Baseline)
+	}
+}
+
+func TestPreviewPublishReportsCacheBaseline(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+	if _, err := client.GetPrivateConfig("app", "cfg"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.setDown(true)
+	preview, err := client.PreviewPublish(&configproto.PublishConfigRequest{AppGroupName: "app", ConfigName: "cfg", Private: "a: 2\n", Format: "yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Baseline.Source != types.SourceCache || len(preview.Modified) != 1 {
+		t.Fatalf("preview = %+v", preview)
+	}
+}
+
+func TestPreviewPublishHasNoSideEffects(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+
+	preview, err := client.PreviewPublish(&configproto.PublishConfigRequest{AppGroupName: "app", ConfigName: "cfg", Private: "a: 2\n", Format: "yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Modified) != 1 || preview.CurrentVersion == "" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if len(client.serviceConfig) != 0 {
+		t.Fatalf("preview stored the service config %v", client.serviceConfig)
+	}
+	if _, err := client.grpcClient.cache.ReadEntry(context.Background(), "app", "cfg"); err == nil {
+		t.Fatal("preview wrote the cache")
+	}
+	if info := client.grpcClient.fetchInfo("app", "cfg"); info.Source != "" {
+		t.Fatalf("preview recorded the fetch %+v", info)
+	}
+}
+
+func TestPreviewPublishValidatesSchema(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "a: 1\n", "yaml")
+	client := server.newClient(t)
+	if err := client.RegisterSchema("app", "cfg", `{"type": "object", "properties": {"a": {"type": "integer"}}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.PreviewPublish(&configproto.PublishConfigRequest{AppGroupName: "app", ConfigName: "cfg", Private: "a: x\n", Format: "yaml"}); err == nil {
+		t.Fatal("preview of a payload not matching the schema succeeded")
+	}
+}
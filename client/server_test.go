@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"ecm-sdk-go/config"
+	configproto "ecm-sdk-go/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testServer is an in-process config server, ListenConfig pushes the config on every Set
+type testServer struct {
+	mu        sync.Mutex
+	configs   map[string]*configproto.Config
+	version   int
+	listeners []chan struct{}
+	down      bool
+	addr      string
+	server    *grpc.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{configs: map[string]*configproto.Config{}, addr: listener.Addr().String()}
+	s.serve(listener)
+	t.Cleanup(func() { s.server.Stop() })
+	return s
+}
+
+func (s *testServer) serve(listener net.Listener) {
+	s.server = grpc.NewServer()
+	configproto.RegisterConfigServiceServer(s.server, s)
+	go s.server.Serve(listener)
+}
+
+// restart serves again on the address of the stopped server
+func (s *testServer) restart(t *testing.T) {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.serve(listener)
+}
+
+// newClient creates a client of the server caching under a temp dir
+func (s *testServer) newClient(t *testing.T, opts ...config.Option) *ConfigClient {
+	opts = append([]config.Option{
+		config.WithClientConfig(config.ClientConfig{EcmServerAddr: s.addr, CachePath: t.TempDir()}),
+		config.WithCredentials(testCredential{}),
+	}, opts...)
+	client, err := NewConfigClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.DeleteConfigClient)
+	return &client
+}
+
+func (s *testServer) set(appGroupName, configName, private, format string) {
+	s.mu.Lock()
+	s.version++
+	s.configs[appGroupName+"/"+configName] = &configproto.Config{
+		Private: private,
+		Format:  format,
+		Version: "v" + strconv.Itoa(s.version),
+	}
+	listeners := s.listeners
+	s.mu.Unlock()
+	for _, listener := range listeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// waitListeners waits until n ListenConfig streams are registered
+func (s *testServer) waitListeners(t *testing.T, n int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s.mu.Lock()
+		count := len(s.listeners)
+		s.mu.Unlock()
+		if count >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d listeners registered, want %d", count, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *testServer) setDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+func (s *testServer) GetConfig(ctx context.Context, in *configproto.ConfigVersion) (*configproto.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+	config, ok := s.configs[in.AppGroupName+"/"+in.ConfigName]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	copied := *config
+	return &copied, nil
+}
+
+func (s *testServer) ListenConfig(stream configproto.ConfigService_ListenConfigServer) error {
+	in, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	pushed := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.listeners = append(s.listeners, pushed)
+	s.mu.Unlock()
+	go func() {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-pushed:
+			s.mu.Lock()
+			config := *s.configs[in.AppGroupName+"/"+in.ConfigName]
+			s.mu.Unlock()
+			if err := stream.Send(&config); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *testServer) PublishConfig(ctx context.Context, in *configproto.PublishConfigRequest) (*configproto.Response, error) {
+	s.mu.Lock()
+	down := s.down
+	s.mu.Unlock()
+	if down {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+	s.set(in.AppGroupName, in.ConfigName, in.Private, in.Format)
+	return &configproto.Response{Result: "success"}, nil
+}
+
+func (s *testServer) PutConfig(stream configproto.ConfigService_PutConfigServer) error {
+	<-stream.Context().Done()
+	return nil
+}
+
+func (s *testServer) DeleteMessage(ctx context.Context, in *configproto.UpdateConfigMessage) (*configproto.Response, error) {
+	return &configproto.Response{Result: "success"}, nil
+}
+
+type testCredential struct{}
+
+func (testCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (testCredential) RequireTransportSecurity() bool { return false }
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/constants"
+	"sync"
+)
+
+// Watch listens the config and delivers every changed key on the returned channel,
+// the channel is closed when the context is done or the server ends the listening.
+// Events wait in a queue of constants.WatchChannelSize events for the consumer, the client never
+// blocks on a slow consumer: when the queue is full the queued events are replaced by one event
+// of kind config.ChangeOverflow, on which the consumer reads the whole config again.
+func (client *ConfigClient) Watch(ctx context.Context, appGroupName, configName string) (<-chan config.ChangeEvent, error) {
+	queue := newEventQueue(constants.WatchChannelSize)
+
+	subscription, err := client.ListenConfigContext(ctx, config.ListenConfigParam{
+		AppGroupName: appGroupName,
+		ConfigName:   configName,
+		OnEvent:      queue.push,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan config.ChangeEvent)
+	go func() {
+		defer close(eventChan)
+		defer subscription.Stop()
+
+		for {
+			event, ok := queue.pop(ctx, subscription.Done())
+			if !ok {
+				return
+			}
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+				return
+			case <-subscription.Done():
+				return
+			}
+		}
+	}()
+
+	return eventChan, nil
+}
+
+// eventQueue keeps up to size events until the consumer takes them
+type eventQueue struct {
+	mutex  sync.Mutex
+	events []config.ChangeEvent
+	size   int
+	notify chan struct{}
+}
+
+func newEventQueue(size int) *eventQueue {
+	return &eventQueue{size: size, notify: make(chan struct{}, 1)}
+}
+
+// push appends the event without blocking, a full queue is replaced by an overflow event
+func (queue *eventQueue) push(event config.ChangeEvent) {
+	queue.mutex.Lock()
+	if len(queue.events) >= queue.size {
+		queue.events = []config.ChangeEvent{{Object: event.Object, Kind: config.ChangeOverflow, Version: event.Version}}
+	}
+	queue.events = append(queue.events, event)
+	queue.mutex.Unlock()
+
+	select {
+	case queue.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for the next event, it returns false when the context is done or the subscription ends
+func (queue *eventQueue) pop(ctx context.Context, done <-chan struct{}) (config.ChangeEvent, bool) {
+	for {
+		queue.mutex.Lock()
+		if len(queue.events) > 0 {
+			event := queue.events[0]
+			queue.events = queue.events[1:]
+			queue.mutex.Unlock()
+			return event, true
+		}
+		queue.mutex.Unlock()
+
+		select {
+		case <-queue.notify:
+		case <-ctx.Done():
+			return config.ChangeEvent{}, false
+		case <-done:
+			return config.ChangeEvent{}, false
+		}
+	}
+}
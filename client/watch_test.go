@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ecm-sdk-go/config"
+	"ecm-sdk-go/constants"
+)
+
+func TestWatchFullChannelDoesNotBlockClient(t *testing.T) {
+	server := newTestServer(t)
+	server.set("app", "cfg", "key000: 0\n", "yaml")
+	client := server.newClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Watch(ctx, "app", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.waitListeners(t, 1)
+
+	// one update with more changed keys than the queue holds, nobody reads the channel
+	var private strings.Builder
+	for i := 0; i < 2*constants.WatchChannelSize; i++ {
+		fmt.Fprintf(&private, "key%03d: %d\n", i, i+1)
+	}
+	server.set("app", "cfg", private.String(), "yaml")
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		content, err := client.GetPrivateConfig("app", "cfg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content == private.String() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("update was not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the dropped events are signalled by an overflow event, the latest are delivered
+	last := fmt.Sprintf("key%03d", 2*constants.WatchChannelSize-1)
+	received, overflow := 0, false
+	for {
+		select {
+		case event := <-events:
+			received++
+			if event.Kind == config.ChangeOverflow {
+				overflow = true
+			}
+			if event.Key != last {
+				continue
+			}
+			if !overflow {
+				t.Fatal("events were dropped without overflow event")
+			}
+			if received > constants.WatchChannelSize+1 {
+				t.Fatalf("received %d events, the queue holds %d", received, constants.WatchChannelSize)
+			}
+			return
+		case <-time.After(10 * time.Second):
+			t.Fatalf("event of %s not received after %d events", last, received)
+		}
+	}
+}
+
+func TestEventQueueOverflow(t *testing.T) {
+	queue := newEventQueue(2)
+	for _, key := range []string{"a", "b", "c"} {
+		queue.push(config.ChangeEvent{Key: key, Kind: config.ChangeAdded})
+	}
+
+	for _, want := range []config.ChangeEvent{{Kind: config.ChangeOverflow}, {Key: "c", Kind: config.ChangeAdded}} {
+		event, ok := queue.pop(context.Background(), nil)
+		if !ok || event != want {
+			t.Fatalf("pop = %+v, %v, want %+v", event, ok, want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := queue.pop(ctx, nil); ok {
+		t.Fatal("pop of an empty queue with a done context returned an event")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/secret"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const usage = `usage:
+  ecm encrypt -key-file <file> [value]   print ENC[...] of the value, read from stdin when omitted,
+                                         the key file defaults to $ENSAASMESH_SECRET_KEY_FILE
+  ecm genkey                             print a new base64 encoded AES-256 key
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "encrypt":
+		encrypt(os.Args[2:])
+	case "genkey":
+		key, err := secret.GenerateKey()
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(key)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func encrypt(args []string) {
+	flags := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keyFile := flags.String("key-file", os.Getenv(constants.SecretKeyFileEnvVar), "file holding the base64 encoded key")
+	flags.Parse(args)
+
+	if *keyFile == "" {
+		fail(fmt.Errorf("the key file is required"))
+	}
+	encrypter, err := secret.NewAESGCMFromKeyFile(*keyFile)
+	if err != nil {
+		fail(err)
+	}
+
+	var plaintext string
+	if flags.NArg() > 0 {
+		plaintext = strings.Join(flags.Args(), " ")
+	} else {
+		content, err := ioutil.ReadAll(bufio.NewReader(os.Stdin))
+		if err != nil {
+			fail(err)
+		}
+		plaintext = strings.TrimRight(string(content), "\r\n")
+	}
+
+	value, err := secret.Wrap(encrypter, plaintext)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Println(value)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "ecm: "+err.Error())
+	os.Exit(1)
+}
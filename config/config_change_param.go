@@ -1,7 +1,82 @@
-package config
-
-type ListenConfigParam struct {
-	AppGroupName string
-	ConfigName   string
-	OnChange     func(object, key, value string)
-}
+package config
+
+import "ecm-sdk-go/types"
+
+type ListenConfigParam struct {
+	AppGroupName string
+	ConfigName   string
+	OnChange     func(object, key, value string)
+	OnEvent      func(event ChangeEvent)
+	// OnChangeSet is called once with all changed keys of one version transition
+	OnChangeSet func(changeSet ChangeSet)
+
+	// OnError is called when a received update is refused, e.g. it does not match the schema,
+	// or when its key values can not be decrypted or interpolated, the raw config is applied then
+	OnError func(err error)
+
+	// KeyPrefixes, KeyGlobs and KeyRegexps limit the callbacks to the matching keys,
+	// a key passes when it matches any of them, without key filters every key passes
+	KeyPrefixes []string
+	// KeyGlobs match dotted keys, "*" matches inside one segment and "**" matches across segments,
+	// a glob matching a key also selects the keys nested below it, e.g. "database.*"
+	KeyGlobs   []string
+	KeyRegexps []string
+	// Objects limits the callbacks to the public, private or services objects, empty means all
+	Objects []string
+}
+
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "modified"
+	ChangeDeleted  ChangeKind = "deleted"
+	// ChangeOverflow is delivered by Watch in place of the events dropped because the consumer fell behind,
+	// it has no key and the consumer should read the whole config again
+	ChangeOverflow ChangeKind = "overflow"
+)
+
+// ChangeEvent describes the change of one key of the public, private or services object
+type ChangeEvent struct {
+	Object   string
+	Key      string
+	OldValue string
+	NewValue string
+	Kind     ChangeKind
+	// Version is the new version of the object, the public version for public and services
+	Version string
+}
+
+// ChangeSet groups the changes of public, private and services of one update
+type ChangeSet struct {
+	OldVersion       string
+	NewVersion       string
+	OldPublicVersion string
+	NewPublicVersion string
+	Added            []ChangeEvent
+	Modified         []ChangeEvent
+	Deleted          []ChangeEvent
+	// Config is the key value config after the update
+	Config *types.KeyValueConfig
+}
+
+// NewChangeSet sorts the events by kind
+func NewChangeSet(events []ChangeEvent) ChangeSet {
+	changeSet := ChangeSet{}
+	for _, event := range events {
+		switch event.Kind {
+		case ChangeAdded:
+			changeSet.Added = append(changeSet.Added, event)
+		case ChangeModified:
+			changeSet.Modified = append(changeSet.Modified, event)
+		case ChangeDeleted:
+			changeSet.Deleted = append(changeSet.Deleted, event)
+		}
+	}
+	return changeSet
+}
+
+// IsEmpty reports whether no key changed
+func (changeSet ChangeSet) IsEmpty() bool {
+	return len(changeSet.Added) == 0 && len(changeSet.Modified) == 0 && len(changeSet.Deleted) == 0
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/types"
+)
+
+// EnvNaming turns a flattened key into an environment variable name, an empty name skips the key
+type EnvNaming func(key string) string
+
+// UpperSnakeCase names "db.host" DB_HOST and "maxConns" MAX_CONNS,
+// every character which is not a letter, digit or underscore becomes an underscore
+func UpperSnakeCase(key string) string {
+	var builder strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToUpper(r))
+		default:
+			builder.WriteByte('_')
+		}
+	}
+
+	// collapse the separators of "a..b" or "hosts[0]"
+	parts := strings.FieldsFunc(builder.String(), func(r rune) bool { return r == '_' })
+	name := strings.Join(parts, "_")
+	if name != "" && unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+	return name
+}
+
+// RawKey keeps the flattened key as the name, the names set before EnvExportParam existed
+func RawKey(key string) string {
+	return key
+}
+
+// EnvExportParam configures the export of the config keys to environment variables
+type EnvExportParam struct {
+	// Naming defaults to UpperSnakeCase
+	Naming EnvNaming
+	// Prefix is put before every name, e.g. "APP_"
+	Prefix string
+	// ObjectPrefixes are put after Prefix for the keys of the public, private or services object,
+	// e.g. {"services": "SVC_"} keeps the service addresses apart from the config keys
+	ObjectPrefixes map[string]string
+
+	// Allow and Deny are key globs as ListenConfigParam.KeyGlobs, without Allow every key is exported
+	// and Deny wins over Allow
+	Allow []string
+	Deny  []string
+	// Objects limits the export to the public, private or services objects, empty means all
+	Objects []string
+}
+
+// EnvExporter sets the environment variables of the config keys, unsets them when the keys are deleted
+type EnvExporter struct {
+	param EnvExportParam
+	allow *KeyFilter
+	deny  *KeyFilter
+}
+
+// NewEnvExporter compiles the allow and deny lists of the param
+func NewEnvExporter(param EnvExportParam) (*EnvExporter, error) {
+	if param.Naming == nil {
+		param.Naming = UpperSnakeCase
+	}
+
+	allow, err := NewKeyFilter(&ListenConfigParam{KeyGlobs: param.Allow, Objects: param.Objects})
+	if err != nil {
+		return nil, err
+	}
+	exporter := &EnvExporter{param: param, allow: allow}
+	if len(param.Deny) > 0 {
+		if exporter.deny, err = NewKeyFilter(&ListenConfigParam{KeyGlobs: param.Deny}); err != nil {
+			return nil, err
+		}
+	}
+	return exporter, nil
+}
+
+// Name returns the environment variable of the key of the object, empty when the key is not exported
+func (exporter *EnvExporter) Name(object, key string) string {
+	if !exporter.allow.Match(object, key) {
+		return ""
+	}
+	if exporter.deny != nil && exporter.deny.Match(object, key) {
+		return ""
+	}
+	name := exporter.param.Naming(key)
+	if name == "" {
+		return ""
+	}
+	return exporter.param.Prefix + exporter.param.ObjectPrefixes[object] + name
+}
+
+// Apply sets or unsets the variables of the changed keys from keyValueConfig, the config after the change.
+// Every affected variable is resolved again over all objects, so a change of a public key does not
+// overwrite the private key of the same name and deleting the private key restores the public one.
+func (exporter *EnvExporter) Apply(events []ChangeEvent, keyValueConfig *types.KeyValueConfig) {
+	values := exporter.values(keyValueConfig)
+	for _, event := range events {
+		name := exporter.Name(event.Object, event.Key)
+		if name == "" {
+			continue
+		}
+		if value, ok := values[name]; ok {
+			os.Setenv(name, value)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+// ExportAll sets the variables of every key of the config, when keys get the same name
+// the private keys win over the public keys and the services keys over both
+func (exporter *EnvExporter) ExportAll(keyValueConfig *types.KeyValueConfig) {
+	values := exporter.values(keyValueConfig)
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		os.Setenv(name, values[name])
+	}
+}
+
+// values returns the value of every exported variable, the objects are applied from the lowest
+// to the highest precedence so the last one wins
+func (exporter *EnvExporter) values(keyValueConfig *types.KeyValueConfig) map[string]string {
+	values := map[string]string{}
+	if keyValueConfig == nil {
+		return values
+	}
+	objects := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{constants.PublicObjectName, keyValueConfig.Public},
+		{constants.PrivateObjectName, keyValueConfig.Private},
+		{constants.ServicesObjectName, keyValueConfig.Services},
+	}
+	for _, object := range objects {
+		// keys of one object sharing a name, e.g. db.host and db_host, resolve in key order
+		keys := make([]string, 0, len(object.values))
+		for key := range object.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if name := exporter.Name(object.name, key); name != "" {
+				values[name] = fmt.Sprintf("%v", object.values[key])
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/types"
+)
+
+func TestUpperSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"db.host":    "DB_HOST",
+		"maxConns":   "MAX_CONNS",
+		"hosts[0]":   "HOSTS_0",
+		"a..b":       "A_B",
+		"0.value":    "_0_VALUE",
+		"http2Proxy": "HTTP2_PROXY",
+	}
+	for key, want := range tests {
+		if got := UpperSnakeCase(key); got != want {
+			t.Errorf("UpperSnakeCase(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEnvExporterPrivateWinsOverPublic(t *testing.T) {
+	exporter, err := NewEnvExporter(EnvExportParam{Prefix: "ENV_EXPORT_TEST_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const name = "ENV_EXPORT_TEST_DB_HOST"
+	defer os.Unsetenv(name)
+
+	config := &types.KeyValueConfig{
+		Public:  map[string]interface{}{"db.host": "public"},
+		Private: map[string]interface{}{"db.host": "private"},
+	}
+	exporter.ExportAll(config)
+	checkEnv(t, name, "private")
+
+	// a change of the public key keeps the private value
+	config.Public = map[string]interface{}{"db.host": "public2"}
+	exporter.Apply([]ChangeEvent{{Object: constants.PublicObjectName, Key: "db.host", NewValue: "public2", Kind: ChangeModified}}, config)
+	checkEnv(t, name, "private")
+
+	// deleting the private key restores the public value
+	config.Private = map[string]interface{}{}
+	exporter.Apply([]ChangeEvent{{Object: constants.PrivateObjectName, Key: "db.host", OldValue: "private", Kind: ChangeDeleted}}, config)
+	checkEnv(t, name, "public2")
+
+	// deleting the public key too unsets the variable
+	config.Public = map[string]interface{}{}
+	exporter.Apply([]ChangeEvent{{Object: constants.PublicObjectName, Key: "db.host", OldValue: "public2", Kind: ChangeDeleted}}, config)
+	if value, ok := os.LookupEnv(name); ok {
+		t.Fatalf("%s = %q after both keys were deleted", name, value)
+	}
+}
+
+func TestEnvExporterAllowDeny(t *testing.T) {
+	exporter, err := NewEnvExporter(EnvExportParam{
+		Prefix:         "APP_",
+		ObjectPrefixes: map[string]string{constants.ServicesObjectName: "SVC_"},
+		Allow:          []string{"db.*", "cache"},
+		Deny:           []string{"db.password"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		object, key, want string
+	}{
+		{constants.PrivateObjectName, "db.host", "APP_DB_HOST"},
+		{constants.PrivateObjectName, "db.password", ""},
+		{constants.PrivateObjectName, "log.level", ""},
+		{constants.ServicesObjectName, "cache", "APP_SVC_CACHE"},
+	}
+	for _, test := range tests {
+		if got := exporter.Name(test.object, test.key); got != test.want {
+			t.Errorf("Name(%s, %s) = %q, want %q", test.object, test.key, got, test.want)
+		}
+	}
+}
+
+func checkEnv(t *testing.T, name, want string) {
+	t.Helper()
+	if value := os.Getenv(name); value != want {
+		t.Fatalf("%s = %q, want %q", name, value, want)
+	}
+}
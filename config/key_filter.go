@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// KeyFilter matches the changed keys against the filters of a ListenConfigParam
+type KeyFilter struct {
+	prefixes []string
+	globs    []*regexp.Regexp
+	regexps  []*regexp.Regexp
+	objects  map[string]bool
+}
+
+// NewKeyFilter compiles the key and object filters of the param
+func NewKeyFilter(param *ListenConfigParam) (*KeyFilter, error) {
+	filter := &KeyFilter{prefixes: param.KeyPrefixes}
+
+	for _, glob := range param.KeyGlobs {
+		re, err := regexp.Compile(globToRegexp(glob))
+		if err != nil {
+			return nil, errors.New("[config.NewKeyFilter] invalid key glob '" + glob + "': " + err.Error())
+		}
+		filter.globs = append(filter.globs, re)
+	}
+
+	for _, expr := range param.KeyRegexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.New("[config.NewKeyFilter] invalid key regexp '" + expr + "': " + err.Error())
+		}
+		filter.regexps = append(filter.regexps, re)
+	}
+
+	if len(param.Objects) > 0 {
+		filter.objects = map[string]bool{}
+		for _, object := range param.Objects {
+			filter.objects[object] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// Match reports whether the key of the object passes the filter
+func (filter *KeyFilter) Match(object, key string) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.objects != nil && !filter.objects[object] {
+		return false
+	}
+	if len(filter.prefixes) == 0 && len(filter.globs) == 0 && len(filter.regexps) == 0 {
+		return true
+	}
+
+	for _, prefix := range filter.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for _, re := range filter.globs {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	for _, re := range filter.regexps {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the events which pass the filter
+func (filter *KeyFilter) Filter(events []ChangeEvent) []ChangeEvent {
+	if filter == nil {
+		return events
+	}
+	result := []ChangeEvent{}
+	for _, event := range events {
+		if filter.Match(event.Object, event.Key) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// globToRegexp translates a dotted key glob, the key may continue below the matched segment
+func globToRegexp(glob string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				builder.WriteString(".*")
+				i++
+			} else {
+				builder.WriteString(`[^.]*`)
+			}
+		case '?':
+			builder.WriteString(`[^.]`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	builder.WriteString(`(\..*)?$`)
+	return builder.String()
+}
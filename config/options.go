@@ -0,0 +1,241 @@
+package config
+
+import (
+	"ecm-sdk-go/cache"
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/secret"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Logger is used by the client to report connection and listen errors
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+// RetryPolicy controls how unary rpcs are retried when the server is unavailable
+// and how fast the client reconnects
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries of one rpc, values below 1 mean a single try
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled after every failure
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries, zero means no cap
+	MaxBackoff time.Duration
+}
+
+// Options holds everything NewConfigClient can be configured with
+type Options struct {
+	Config               *Config
+	DialOptions          []grpc.DialOption
+	UnaryInterceptors    []grpc.UnaryClientInterceptor
+	StreamInterceptors   []grpc.StreamClientInterceptor
+	TransportCredentials credentials.TransportCredentials
+	Credentials          credentials.PerRPCCredentials
+	Logger               Logger
+	Store                cache.Store
+	CacheCipher          cache.Cipher
+	CacheOldCipher       cache.Cipher
+	RPCTimeout           time.Duration
+	RetryPolicy          RetryPolicy
+	Interpolate          bool
+	Decrypter            secret.Decrypter
+	CanonicalPublish     bool
+	HistorySize          int
+	MaxStaleness         time.Duration
+	EnvExporter          *EnvExporter
+
+	// ConfigErr is the validation error of the client config of WithClientConfig, NewConfigClient returns it
+	ConfigErr error
+}
+
+// Option configures the client created by NewConfigClient
+type Option interface {
+	Apply(options *Options)
+}
+
+// OptionFunc adapts a function to the Option interface
+type OptionFunc func(options *Options)
+
+func (f OptionFunc) Apply(options *Options) {
+	f(options)
+}
+
+// Apply makes *Config an Option, so NewConfigClient(&conf) keeps working
+func (config *Config) Apply(options *Options) {
+	options.Config = config
+	options.ConfigErr = nil
+}
+
+// NewOptions applies opts over the default options
+func NewOptions(opts ...Option) *Options {
+	options := &Options{
+		Logger:      stdLogger{},
+		HistorySize: constants.HistorySize,
+		RetryPolicy: RetryPolicy{
+			MaxAttempts:    1,
+			InitialBackoff: time.Second,
+		},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt.Apply(options)
+		}
+	}
+	return options
+}
+
+// WithClientConfig validates and uses the client config, NewConfigClient fails with the validation error
+func WithClientConfig(clientConfig ClientConfig) Option {
+	return OptionFunc(func(options *Options) {
+		config := &Config{}
+		options.ConfigErr = config.SetClientConfig(clientConfig)
+		options.Config = config
+	})
+}
+
+// WithDialOptions appends extra grpc dial options
+func WithDialOptions(dialOptions ...grpc.DialOption) Option {
+	return OptionFunc(func(options *Options) {
+		options.DialOptions = append(options.DialOptions, dialOptions...)
+	})
+}
+
+// WithUnaryInterceptors appends interceptors of GetConfig, PublishConfig and DeleteMessage
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return OptionFunc(func(options *Options) {
+		options.UnaryInterceptors = append(options.UnaryInterceptors, interceptors...)
+	})
+}
+
+// WithStreamInterceptors appends interceptors of the ListenConfig and PutConfig streams
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) Option {
+	return OptionFunc(func(options *Options) {
+		options.StreamInterceptors = append(options.StreamInterceptors, interceptors...)
+	})
+}
+
+// WithTransportCredentials replaces the default insecure transport
+func WithTransportCredentials(transportCredentials credentials.TransportCredentials) Option {
+	return OptionFunc(func(options *Options) {
+		options.TransportCredentials = transportCredentials
+	})
+}
+
+// WithCredentials replaces the default credentials read from the backend register information
+func WithCredentials(perRPCCredentials credentials.PerRPCCredentials) Option {
+	return OptionFunc(func(options *Options) {
+		options.Credentials = perRPCCredentials
+	})
+}
+
+// WithLogger replaces the standard logger
+func WithLogger(logger Logger) Option {
+	return OptionFunc(func(options *Options) {
+		if logger != nil {
+			options.Logger = logger
+		}
+	})
+}
+
+// WithStore keeps the cache in the store instead of the files under ClientConfig.CachePath,
+// e.g. cache.NewMemoryStore() on a read-only filesystem. The store keeps the history and the fetch
+// times of the configs, which History and the MaxStaleness check of the cache reads rely on.
+func WithStore(store cache.Store) Option {
+	return OptionFunc(func(options *Options) {
+		options.Store = store
+	})
+}
+
+// WithCacheCipher encrypts the default file cache, without it the key is read from
+// ENSAASMESH_CACHE_KEY or ENSAASMESH_CACHE_KEY_FILE, see cache.NewCipherFromEnv.
+// The files written in plaintext before are encrypted or removed when the client starts.
+// A store set with WithStore is encrypted by setting its own Cipher.
+func WithCacheCipher(cipher cache.Cipher) Option {
+	return OptionFunc(func(options *Options) {
+		options.CacheCipher = cipher
+	})
+}
+
+// WithCacheRekey re-encrypts the default file cache written with the old cipher when the client starts,
+// without it the old key is read from ENSAASMESH_CACHE_KEY_OLD or ENSAASMESH_CACHE_KEY_OLD_FILE,
+// see cache.NewOldCipherFromEnv. The new key is set with WithCacheCipher or the environment,
+// without new key the cache is rewritten in plaintext.
+func WithCacheRekey(old cache.Cipher) Option {
+	return OptionFunc(func(options *Options) {
+		options.CacheOldCipher = old
+	})
+}
+
+// WithRPCTimeout bounds every unary rpc whose context has no deadline
+func WithRPCTimeout(timeout time.Duration) Option {
+	return OptionFunc(func(options *Options) {
+		options.RPCTimeout = timeout
+	})
+}
+
+// WithRetryPolicy replaces the default policy of a single try
+func WithRetryPolicy(retryPolicy RetryPolicy) Option {
+	return OptionFunc(func(options *Options) {
+		options.RetryPolicy = retryPolicy
+	})
+}
+
+// WithInterpolation resolves ${key}, ${PUBLIC:key}, ${ENV:NAME} and ${ref:-fallback} references
+// in the key value config and in the change notifications, see interpolate.Resolve
+func WithInterpolation() Option {
+	return OptionFunc(func(options *Options) {
+		options.Interpolate = true
+	})
+}
+
+// WithDecrypter decrypts the ENC[...] values of the key value config on the client,
+// the cache keeps the encrypted values. Without it the values are decrypted with the AES-GCM key file
+// named by ENSAASMESH_SECRET_KEY_FILE when it is set.
+func WithDecrypter(decrypter secret.Decrypter) Option {
+	return OptionFunc(func(options *Options) {
+		options.Decrypter = decrypter
+	})
+}
+
+// WithCanonicalPublish rewrites the private object of PublishConfig with sorted keys
+// and consistent indentation before it is sent, see utils.CanonicalizeConfig
+func WithCanonicalPublish() Option {
+	return OptionFunc(func(options *Options) {
+		options.CanonicalPublish = true
+	})
+}
+
+// WithHistorySize sets how many received versions of every config the file cache keeps
+// for History and Rollback, 0 disables the history
+func WithHistorySize(size int) Option {
+	return OptionFunc(func(options *Options) {
+		options.HistorySize = size
+	})
+}
+
+// WithMaxStaleness makes the reads fail with a client.StaleConfigError instead of serving a cached config
+// fetched longer than maxStaleness ago, a cached config with an unknown fetch time is refused too,
+// so a Store set with WithStore must keep Entry.FetchedAt
+func WithMaxStaleness(maxStaleness time.Duration) Option {
+	return OptionFunc(func(options *Options) {
+		options.MaxStaleness = maxStaleness
+	})
+}
+
+// WithEnvExport exports the config keys to environment variables with the naming, allow and deny lists
+// of the exporter, it replaces the raw key names set by ClientConfig.UpdateEnvWhenChanged
+func WithEnvExport(exporter *EnvExporter) Option {
+	return OptionFunc(func(options *Options) {
+		options.EnvExporter = exporter
+	})
+}
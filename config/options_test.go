@@ -0,0 +1,22 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithClientConfigKeepsValidationError(t *testing.T) {
+	options := NewOptions(WithClientConfig(ClientConfig{EcmServerAddr: "no-port"}))
+	if options.ConfigErr == nil || !strings.Contains(options.ConfigErr.Error(), "invalid") {
+		t.Fatalf("ConfigErr = %v", options.ConfigErr)
+	}
+
+	// a later valid config replaces the invalid one
+	options = NewOptions(
+		WithClientConfig(ClientConfig{EcmServerAddr: "no-port"}),
+		WithClientConfig(ClientConfig{EcmServerAddr: "127.0.0.1:8080", CachePath: t.TempDir()}),
+	)
+	if options.ConfigErr != nil {
+		t.Fatalf("ConfigErr = %v", options.ConfigErr)
+	}
+}
@@ -1,21 +1,29 @@
-package constants
-
-const (
-	BackendRegisterInfoPath           = "/mosn/register"
-	EnvPrefix                         = "ENSAASMESH_"
-	EcmServerAddrEnvVar               = EnvPrefix + "CONFIG_ADDR" // sidecar auto inject
-	CachePathEnvVar                   = EnvPrefix + "CACHE_PATH"
-	UpdateEnvWhenChangedEnvVar        = EnvPrefix + "UPDATE_ENV_WHEN_CHANGED"
-	ListenIntervalEnvVar              = EnvPrefix + "LISTEN_INTERNAL"
-	CachePath                         = "global_cache"
-	CachFileName                      = "config"
-	UpdateEnvWhenChanged              = true
-	NotLoadCacheAtStart               = true
-	ListenInterval             uint64 = 10 //unit: s
-	PublicObjectName                  = "public"
-	PrivateObjectName                 = "private"
-	ServicesObjectName                = "services"
-	GrpcResponseSuccess               = "success"
-	HeartBeatPackage                  = "\n"
-	HeartBeatInterval                 = 40
-)
+package constants
+
+const (
+	BackendRegisterInfoPath           = "/mosn/register"
+	EnvPrefix                         = "ENSAASMESH_"
+	EcmServerAddrEnvVar               = EnvPrefix + "CONFIG_ADDR" // sidecar auto inject
+	CachePathEnvVar                   = EnvPrefix + "CACHE_PATH"
+	UpdateEnvWhenChangedEnvVar        = EnvPrefix + "UPDATE_ENV_WHEN_CHANGED"
+	ListenIntervalEnvVar              = EnvPrefix + "LISTEN_INTERNAL"
+	LoadCacheAtStartEnvVar            = EnvPrefix + "LOAD_CACHE_AT_START"
+	CacheKeyEnvVar                    = EnvPrefix + "CACHE_KEY"
+	CacheKeyFileEnvVar                = EnvPrefix + "CACHE_KEY_FILE"
+	CacheKeyOldEnvVar                 = EnvPrefix + "CACHE_KEY_OLD"
+	CacheKeyOldFileEnvVar             = EnvPrefix + "CACHE_KEY_OLD_FILE"
+	SecretKeyFileEnvVar               = EnvPrefix + "SECRET_KEY_FILE"
+	CachePath                         = "global_cache"
+	CachFileName                      = "config"
+	UpdateEnvWhenChanged              = true
+	NotLoadCacheAtStart               = true
+	ListenInterval             uint64 = 10 //unit: s
+	PublicObjectName                  = "public"
+	PrivateObjectName                 = "private"
+	ServicesObjectName                = "services"
+	GrpcResponseSuccess               = "success"
+	HeartBeatPackage                  = "\n"
+	HeartBeatInterval                 = 40
+	WatchChannelSize                  = 64
+	HistorySize                       = 10
+)
@@ -0,0 +1,181 @@
+package decode
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToString formats any decoded config value as a string
+func ToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// ToInt64 converts the numbers produced by the json, yaml and toml decoders to int64
+func ToInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int64", v)
+		}
+		return int64(v), nil
+	case float32:
+		return floatToInt64(float64(v))
+	case float64:
+		return floatToInt64(v)
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return floatToInt64(f)
+		}
+		return 0, fmt.Errorf("cannot convert '%s' to int", v)
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to int", value)
+	}
+}
+
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("value %v is not an integer", f)
+	}
+	if f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("value %v overflows int64", f)
+	}
+	return int64(f), nil
+}
+
+// ToFloat64 converts a decoded config value to float64
+func ToFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert '%s' to float", v)
+		}
+		return f, nil
+	default:
+		i, err := ToInt64(value)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %T to float", value)
+		}
+		return float64(i), nil
+	}
+}
+
+// ToBool converts a decoded config value to bool
+func ToBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("cannot convert '%s' to bool", v)
+		}
+		return b, nil
+	default:
+		i, err := ToInt64(value)
+		if err != nil {
+			return false, fmt.Errorf("cannot convert %T to bool", value)
+		}
+		return i != 0, nil
+	}
+}
+
+// ToDuration converts a decoded config value to time.Duration,
+// strings use the time.ParseDuration syntax and numbers are nanoseconds
+func ToDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert '%s' to duration", v)
+		}
+		return d, nil
+	default:
+		i, err := ToInt64(value)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %T to duration", value)
+		}
+		return time.Duration(i), nil
+	}
+}
+
+// ToTime converts a decoded config value to time.Time,
+// strings must be RFC 3339 and numbers are unix seconds
+func ToTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(v))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot convert '%s' to time", v)
+		}
+		return t, nil
+	default:
+		i, err := ToInt64(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot convert %T to time", value)
+		}
+		return time.Unix(i, 0), nil
+	}
+}
+
+// ToStringSlice splits a comma separated string, empty items are dropped
+func ToStringSlice(s string) []string {
+	result := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,295 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TagName is the struct tag used to bind a field to a flattened config key
+const TagName = "ecm"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DecodeError reports the key whose value could not be decoded
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("[decode.Unmarshal] key '%s': %s", e.Key, e.Err.Error())
+}
+
+// Unmarshal decodes the flattened key value map, as produced by utils.ParseConfigToMap,
+// into out which must be a non-nil pointer to a struct.
+//
+// Fields are bound with the `ecm:"db.host"` tag, untagged fields use the field name.
+// Keys of nested structs are joined to the key of the parent field with a dot,
+// slices are read from the "key.0", "key.1" ... entries, which must be contiguous from 0,
+// and maps from "key.<name>".
+func Unmarshal(values map[string]interface{}, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("[decode.Unmarshal] out must be a non-nil pointer")
+	}
+	if rv.Elem().Kind() != reflect.Struct {
+		return errors.New("[decode.Unmarshal] out must point to a struct")
+	}
+
+	return decodeStruct(values, "", rv.Elem())
+}
+
+func decodeStruct(values map[string]interface{}, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(TagName); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+
+		// embedded structs without tag share the key space of the parent
+		key := joinKey(prefix, name)
+		if field.Anonymous && field.Tag.Get(TagName) == "" {
+			key = prefix
+		}
+
+		if err := decodeValue(values, key, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeValue(values map[string]interface{}, key string, rv reflect.Value) error {
+	if !hasKey(values, key) {
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(values, key, rv.Elem())
+	case reflect.Struct:
+		if rv.Type() != reflect.TypeOf(time.Time{}) {
+			return decodeStruct(values, key, rv)
+		}
+	case reflect.Slice:
+		if _, ok := values[key]; !ok {
+			return decodeSlice(values, key, rv)
+		}
+	case reflect.Map:
+		return decodeMap(values, key, rv)
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return nil
+	}
+	if err := Assign(value, rv); err != nil {
+		return &DecodeError{Key: key, Err: err}
+	}
+	return nil
+}
+
+func decodeSlice(values map[string]interface{}, key string, rv reflect.Value) error {
+	indexes := []int{}
+	for _, child := range childKeys(values, key) {
+		index, err := strconv.Atoi(child)
+		if err != nil || index < 0 || strconv.Itoa(index) != child {
+			return &DecodeError{Key: joinKey(key, child), Err: errors.New("slice index must be a non-negative integer")}
+		}
+		indexes = append(indexes, index)
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	sort.Ints(indexes)
+
+	// the length comes from the config, a sparse index like list.99999999999 must not allocate it
+	for i, index := range indexes {
+		if index != i {
+			return &DecodeError{Key: joinKey(key, strconv.Itoa(index)), Err: errors.New("slice indexes must be contiguous from 0")}
+		}
+	}
+
+	slice := reflect.MakeSlice(rv.Type(), indexes[len(indexes)-1]+1, indexes[len(indexes)-1]+1)
+	for _, index := range indexes {
+		if err := decodeValue(values, joinKey(key, strconv.Itoa(index)), slice.Index(index)); err != nil {
+			return err
+		}
+	}
+	rv.Set(slice)
+	return nil
+}
+
+func decodeMap(values map[string]interface{}, key string, rv reflect.Value) error {
+	mapType := rv.Type()
+	if mapType.Key().Kind() != reflect.String {
+		return &DecodeError{Key: key, Err: fmt.Errorf("unsupported map key type %s", mapType.Key())}
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(mapType))
+	}
+
+	// interface{} values take the rest of the flattened key
+	if mapType.Elem().Kind() == reflect.Interface {
+		keyPrefix := key + "."
+		for k, value := range values {
+			if strings.HasPrefix(k, keyPrefix) {
+				rv.SetMapIndex(reflect.ValueOf(k[len(keyPrefix):]).Convert(mapType.Key()), reflect.ValueOf(value))
+			}
+		}
+		return nil
+	}
+
+	for _, child := range childKeys(values, key) {
+		elem := reflect.New(mapType.Elem()).Elem()
+		if err := decodeValue(values, joinKey(key, child), elem); err != nil {
+			return err
+		}
+		rv.SetMapIndex(reflect.ValueOf(child).Convert(mapType.Key()), elem)
+	}
+	return nil
+}
+
+// Assign converts value to the type of rv and stores it
+func Assign(value interface{}, rv reflect.Value) error {
+	if value == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	if rv.Type() == durationType {
+		d, err := ToDuration(value)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+	if rv.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := ToTime(value)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(ToString(value))
+	case reflect.Bool:
+		b, err := ToBool(value)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := ToInt64(value)
+		if err != nil {
+			return err
+		}
+		if rv.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, rv.Type())
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := ToInt64(value)
+		if err != nil {
+			return err
+		}
+		if i < 0 || rv.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows %s", i, rv.Type())
+		}
+		rv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := ToFloat64(value)
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(value))
+	case reflect.Slice:
+		// a comma separated string is accepted for slices of scalars
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to %s", value, rv.Type())
+		}
+		parts := ToStringSlice(s)
+		slice := reflect.MakeSlice(rv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := Assign(part, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+func hasKey(values map[string]interface{}, key string) bool {
+	if key == "" {
+		return len(values) > 0
+	}
+	if _, ok := values[key]; ok {
+		return true
+	}
+	keyPrefix := key + "."
+	for k := range values {
+		if strings.HasPrefix(k, keyPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// childKeys returns the distinct next key segments below key
+func childKeys(values map[string]interface{}, key string) []string {
+	keyPrefix := key + "."
+	seen := map[string]bool{}
+	children := []string{}
+	for k := range values {
+		if !strings.HasPrefix(k, keyPrefix) {
+			continue
+		}
+		child := k[len(keyPrefix):]
+		if i := strings.Index(child, "."); i >= 0 {
+			child = child[:i]
+		}
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
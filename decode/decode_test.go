@@ -0,0 +1,75 @@
+package decode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type server struct {
+	Host string `ecm:"host"`
+	Port int    `ecm:"port"`
+}
+
+type settings struct {
+	Name    string            `ecm:"name"`
+	Timeout time.Duration     `ecm:"timeout"`
+	Tags    []string          `ecm:"tags"`
+	Servers []server          `ecm:"servers"`
+	Labels  map[string]string `ecm:"labels"`
+	DB      *server           `ecm:"db"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	values := map[string]interface{}{
+		"name":           "demo",
+		"timeout":        "5s",
+		"tags":           "a, b",
+		"servers.0.host": "h0",
+		"servers.0.port": 1,
+		"servers.1.host": "h1",
+		"labels.env":     "prod",
+		"db.host":        "db",
+		"db.port":        "5432",
+	}
+	var out settings
+	if err := Unmarshal(values, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := settings{
+		Name:    "demo",
+		Timeout: 5 * time.Second,
+		Tags:    []string{"a", "b"},
+		Servers: []server{{Host: "h0", Port: 1}, {Host: "h1"}},
+		Labels:  map[string]string{"env": "prod"},
+		DB:      &server{Host: "db", Port: 5432},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("got %+v, want %+v", out, want)
+	}
+}
+
+func TestUnmarshalSliceIndexes(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		err    string
+	}{
+		{"huge index", map[string]interface{}{"tags.99999999999": "x"}, "contiguous"},
+		{"sparse", map[string]interface{}{"tags.0": "a", "tags.2": "c"}, "contiguous"},
+		{"not from zero", map[string]interface{}{"tags.1": "b"}, "contiguous"},
+		{"negative", map[string]interface{}{"tags.-1": "a"}, "non-negative"},
+		{"leading zero", map[string]interface{}{"tags.0": "a", "tags.01": "b"}, "non-negative"},
+		{"name", map[string]interface{}{"tags.first": "a"}, "non-negative"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out settings
+			err := Unmarshal(test.values, &out)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Marshal converts a struct or a map into nested maps which can be encoded as json, yaml or toml.
+// It is the reverse of Unmarshal: fields are named by the `ecm` tag or the field name,
+// a dotted tag such as `ecm:"db.host"` creates the nested objects, nil pointers are omitted,
+// durations are written as strings such as "1m30s" and times as RFC 3339 strings.
+func Marshal(in interface{}) (map[string]interface{}, error) {
+	rv := indirect(reflect.ValueOf(in))
+	if !rv.IsValid() || (rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map) {
+		return nil, errors.New("[decode.Marshal] in must be a struct or a map")
+	}
+
+	value, err := encodeValue(rv, "")
+	if err != nil {
+		return nil, err
+	}
+	result, _ := value.(map[string]interface{})
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
+
+func encodeValue(rv reflect.Value, key string) (interface{}, error) {
+	rv = indirect(rv)
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	if rv.Type() == durationType {
+		return time.Duration(rv.Int()).String(), nil
+	}
+	if t, ok := rv.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		result := map[string]interface{}{}
+		if err := encodeStruct(rv, key, result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case reflect.Map:
+		result := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			name := fmt.Sprintf("%v", iter.Key().Interface())
+			value, err := encodeValue(iter.Value(), joinKey(key, name))
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				result[name] = value
+			}
+		}
+		return result, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		result := make([]interface{}, rv.Len())
+		for i := range result {
+			value, err := encodeValue(rv.Index(i), joinKey(key, fmt.Sprintf("%d", i)))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = value
+		}
+		return result, nil
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return rv.Interface(), nil
+	default:
+		return nil, fmt.Errorf("[decode.Marshal] key '%s': unsupported type %s", key, rv.Type())
+	}
+}
+
+func encodeStruct(rv reflect.Value, prefix string, result map[string]interface{}) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(TagName); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+
+		// embedded structs without tag share the key space of the parent
+		if field.Anonymous && field.Tag.Get(TagName) == "" {
+			embedded := indirect(rv.Field(i))
+			if embedded.IsValid() && embedded.Kind() == reflect.Struct {
+				if err := encodeStruct(embedded, prefix, result); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		value, err := encodeValue(rv.Field(i), joinKey(prefix, name))
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			setPath(result, strings.Split(name, "."), value)
+		}
+	}
+	return nil
+}
+
+// setPath stores value below the dotted path, creating the intermediate objects
+func setPath(result map[string]interface{}, path []string, value interface{}) {
+	for _, segment := range path[:len(path)-1] {
+		child, ok := result[segment].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			result[segment] = child
+		}
+		result = child
+	}
+	result[path[len(path)-1]] = value
+}
+
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
@@ -0,0 +1,156 @@
+package interpolate
+
+import (
+	"ecm-sdk-go/decode"
+	"errors"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvPrefix marks a reference to an environment variable, e.g. ${ENV:HOSTNAME}
+	EnvPrefix = "ENV:"
+	// PublicPrefix marks a reference to a key of the public object, e.g. ${PUBLIC:db.host}
+	PublicPrefix = "PUBLIC:"
+	// DefaultSeparator separates a reference from its fallback, e.g. ${db.port:-5432}
+	DefaultSeparator = ":-"
+)
+
+// Resolve expands the ${...} references in the string values of the flattened object.
+// ${key} refers to another key of the object, ${PUBLIC:key} to a key of public,
+// ${ENV:NAME} to an environment variable and ${ref:-fallback} is used when ref is not set.
+// A value which is a single reference keeps the type of the referenced value, $${ escapes a literal ${.
+func Resolve(values, public map[string]interface{}) (map[string]interface{}, error) {
+	r := &resolver{
+		values:   values,
+		public:   public,
+		resolved: map[string]interface{}{},
+		visiting: map[string]bool{},
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key := range values {
+		value, err := r.resolveKey(key, nil)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+type resolver struct {
+	values   map[string]interface{}
+	public   map[string]interface{}
+	resolved map[string]interface{}
+	visiting map[string]bool
+}
+
+func (r *resolver) resolveKey(key string, path []string) (interface{}, error) {
+	if value, ok := r.resolved[key]; ok {
+		return value, nil
+	}
+	if r.visiting[key] {
+		return nil, errors.New("[interpolate.Resolve] reference cycle: " + strings.Join(append(path, key), " -> "))
+	}
+
+	r.visiting[key] = true
+	value, err := r.resolveValue(r.values[key], append(append([]string{}, path...), key))
+	delete(r.visiting, key)
+	if err != nil {
+		return nil, err
+	}
+
+	r.resolved[key] = value
+	return value, nil
+}
+
+func (r *resolver) resolveValue(value interface{}, path []string) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok || !strings.Contains(s, "${") {
+		return value, nil
+	}
+
+	// a single reference keeps the type of the referenced value
+	if exprs := expressions(s); len(exprs) == 1 && s == "${"+exprs[0]+"}" {
+		return r.resolveExpression(exprs[0], path)
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			builder.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			builder.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := strings.Index(s[i:], "}")
+		if end < 0 {
+			return nil, errors.New("[interpolate.Resolve] unclosed reference in key '" + path[len(path)-1] + "'")
+		}
+		resolved, err := r.resolveExpression(s[i+2:i+end], path)
+		if err != nil {
+			return nil, err
+		}
+		builder.WriteString(decode.ToString(resolved))
+		i += end + 1
+	}
+	return builder.String(), nil
+}
+
+func (r *resolver) resolveExpression(expr string, path []string) (interface{}, error) {
+	name, fallback, hasDefault := splitDefault(expr)
+
+	switch {
+	case strings.HasPrefix(name, EnvPrefix):
+		if value, ok := os.LookupEnv(strings.TrimPrefix(name, EnvPrefix)); ok {
+			return value, nil
+		}
+	case strings.HasPrefix(name, PublicPrefix):
+		if value, ok := r.public[strings.TrimPrefix(name, PublicPrefix)]; ok {
+			return value, nil
+		}
+	default:
+		if _, ok := r.values[name]; ok {
+			return r.resolveKey(name, path)
+		}
+	}
+
+	if hasDefault {
+		return fallback, nil
+	}
+	return nil, errors.New("[interpolate.Resolve] unresolved reference '" + name + "' in key '" + path[len(path)-1] + "'")
+}
+
+// expressions returns the contents of the ${...} references of the value
+func expressions(s string) []string {
+	exprs := []string{}
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			i++
+			continue
+		}
+		end := strings.Index(s[i:], "}")
+		if end < 0 {
+			break
+		}
+		exprs = append(exprs, s[i+2:i+end])
+		i += end + 1
+	}
+	return exprs
+}
+
+func splitDefault(expr string) (string, string, bool) {
+	if i := strings.Index(expr, DefaultSeparator); i >= 0 {
+		return strings.TrimSpace(expr[:i]), expr[i+len(DefaultSeparator):], true
+	}
+	return strings.TrimSpace(expr), "", false
+}
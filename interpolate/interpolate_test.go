@@ -0,0 +1,51 @@
+package interpolate
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	os.Setenv("INTERPOLATE_TEST_HOST", "db.local")
+	defer os.Unsetenv("INTERPOLATE_TEST_HOST")
+
+	values := map[string]interface{}{
+		"db.host": "${ENV:INTERPOLATE_TEST_HOST}",
+		"db.port": "${PUBLIC:port}",
+		"db.url":  "${db.host}:${db.port}",
+		"db.user": "${ENV:INTERPOLATE_TEST_MISSING:-admin}",
+		"literal": "$${db.host}",
+	}
+	public := map[string]interface{}{"port": 5432}
+
+	resolved, err := Resolve(values, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"db.host": "db.local",
+		"db.port": 5432,
+		"db.url":  "db.local:5432",
+		"db.user": "admin",
+		"literal": "${db.host}",
+	}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Fatalf("resolved = %v, want %v", resolved, want)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unresolved": {"a": "${ENV:INTERPOLATE_TEST_MISSING}"},
+		"cycle":      {"a": "${b}", "b": "x ${a}"},
+		"unclosed":   {"a": "x ${b"},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Resolve(values, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
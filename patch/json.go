@@ -0,0 +1,246 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonNode is a json value which keeps the order of the object keys
+// and the text of the scalars as written in the source
+type jsonNode struct {
+	object bool
+	array  bool
+	keys   []string
+	values []*jsonNode
+	raw    string
+}
+
+type jsonEditor struct {
+	root   *jsonNode
+	indent string
+}
+
+func newJSONEditor(config string) (*jsonEditor, error) {
+	e := &jsonEditor{root: &jsonNode{object: true}, indent: jsonIndent(config)}
+	if strings.TrimSpace(config) == "" {
+		return e, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(config))
+	decoder.UseNumber()
+	root, err := parseJSONNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if !root.object {
+		return nil, errors.New("[patch.Apply] the json document is not an object")
+	}
+	e.root = root
+	return e, nil
+}
+
+func (e *jsonEditor) set(path []string, value interface{}) error {
+	valueNode, err := newJSONNode(value)
+	if err != nil {
+		return err
+	}
+
+	node := e.root
+	for i, segment := range path {
+		last := i == len(path)-1
+		index, err := node.index(segment, true)
+		if err != nil {
+			return errors.New("[patch.Apply] " + err.Error() + " in key '" + strings.Join(path, ".") + "'")
+		}
+		if index == len(node.values) {
+			if node.object {
+				node.keys = append(node.keys, segment)
+			}
+			node.values = append(node.values, &jsonNode{object: true})
+		}
+		if last {
+			node.values[index] = valueNode
+			return nil
+		}
+		if child := node.values[index]; !child.object && !child.array {
+			node.values[index] = &jsonNode{object: true}
+		}
+		node = node.values[index]
+	}
+	return nil
+}
+
+func (e *jsonEditor) delete(path []string) error {
+	node := e.root
+	for i, segment := range path {
+		index, err := node.index(segment, false)
+		if err != nil || index < 0 {
+			return nil
+		}
+		if i == len(path)-1 {
+			if node.object {
+				node.keys = append(node.keys[:index], node.keys[index+1:]...)
+			}
+			node.values = append(node.values[:index], node.values[index+1:]...)
+			return nil
+		}
+		node = node.values[index]
+	}
+	return nil
+}
+
+func (e *jsonEditor) encode() (string, error) {
+	var buffer bytes.Buffer
+	e.root.write(&buffer, e.indent, "")
+	if e.indent != "" {
+		buffer.WriteString("\n")
+	}
+	return buffer.String(), nil
+}
+
+// index returns the position of the key or list index, len(values) when it may be appended and -1 when it is missing
+func (node *jsonNode) index(segment string, create bool) (int, error) {
+	switch {
+	case node.object:
+		for i, key := range node.keys {
+			if key == segment {
+				return i, nil
+			}
+		}
+		if create {
+			return len(node.values), nil
+		}
+		return -1, nil
+	case node.array:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index > len(node.values) || (!create && index == len(node.values)) {
+			return -1, errors.New("invalid list index '" + segment + "'")
+		}
+		return index, nil
+	default:
+		return -1, nil
+	}
+}
+
+func (node *jsonNode) write(buffer *bytes.Buffer, indent, prefix string) {
+	if !node.object && !node.array {
+		buffer.WriteString(node.raw)
+		return
+	}
+
+	open, close := "[", "]"
+	if node.object {
+		open, close = "{", "}"
+	}
+	buffer.WriteString(open)
+	if len(node.values) == 0 {
+		buffer.WriteString(close)
+		return
+	}
+
+	childPrefix := prefix + indent
+	for i, value := range node.values {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		if indent != "" {
+			buffer.WriteString("\n" + childPrefix)
+		}
+		if node.object {
+			key, _ := marshalJSON(node.keys[i])
+			buffer.Write(key)
+			buffer.WriteString(":")
+			if indent != "" {
+				buffer.WriteString(" ")
+			}
+		}
+		value.write(buffer, indent, childPrefix)
+	}
+	if indent != "" {
+		buffer.WriteString("\n" + prefix)
+	}
+	buffer.WriteString(close)
+}
+
+func newJSONNode(value interface{}) (*jsonNode, error) {
+	content, err := marshalJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	return parseJSONNode(decoder)
+}
+
+func parseJSONNode(decoder *json.Decoder) (*jsonNode, error) {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return nil, errors.New("[patch.Apply] unexpected end of json")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		node := &jsonNode{object: t == '{', array: t == '['}
+		for decoder.More() {
+			if node.object {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			value, err := parseJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		}
+		// the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case json.Number:
+		return &jsonNode{raw: t.String()}, nil
+	case nil:
+		return &jsonNode{raw: "null"}, nil
+	default:
+		raw, err := marshalJSON(t)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{raw: string(raw)}, nil
+	}
+}
+
+// marshalJSON is json.Marshal without the escaping of <, > and &
+func marshalJSON(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// jsonIndent returns the indentation unit of the document, empty for a document on one line
+func jsonIndent(config string) string {
+	for _, line := range strings.Split(config, "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && trimmed != line {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	if config = strings.TrimSpace(config); config != "" && !strings.Contains(config, "\n") {
+		return ""
+	}
+	return "  "
+}
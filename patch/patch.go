@@ -0,0 +1,181 @@
+package patch
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"ecm-sdk-go/decode"
+	"ecm-sdk-go/utils"
+)
+
+// OpType is the kind of a patch operation
+type OpType string
+
+const (
+	// OpSet replaces the value of the key, missing parents are created
+	OpSet OpType = "set"
+	// OpDelete removes the key and everything below it, a missing key is ignored
+	OpDelete OpType = "delete"
+	// OpMerge merges a map into the object at the key, nested maps are merged recursively
+	OpMerge OpType = "merge"
+)
+
+// Operation edits one dotted key of a config document, a numeric segment such as
+// "servers.0.host" indexes a list
+type Operation struct {
+	Op    OpType
+	Key   string
+	Value interface{}
+}
+
+// Set returns an operation which sets key to value
+func Set(key string, value interface{}) Operation {
+	return Operation{Op: OpSet, Key: key, Value: value}
+}
+
+// Delete returns an operation which removes key
+func Delete(key string) Operation {
+	return Operation{Op: OpDelete, Key: key}
+}
+
+// Merge returns an operation which merges values into the object at key
+func Merge(key string, values map[string]interface{}) Operation {
+	return Operation{Op: OpMerge, Key: key, Value: values}
+}
+
+// editor changes a parsed document in place, merge is done with set on every leaf
+type editor interface {
+	set(path []string, value interface{}) error
+	delete(path []string) error
+	encode() (string, error)
+}
+
+// Apply runs the operations on the config source in the format and returns the edited source.
+// Comments and key order are kept in yaml, key order and number literals in json, comments
+// and layout in toml. A toml edit which does not fit the line structure of the document fails,
+// e.g. a key inside an array of tables, and so does a yaml edit below an alias.
+func Apply(config, format string, ops []Operation) (string, error) {
+	name, err := utils.NormalizeFormat(format)
+	if err != nil {
+		return "", errors.New("[patch.Apply] " + err.Error())
+	}
+	if err := utils.ValidateConfig(config, name); err != nil {
+		return "", err
+	}
+
+	var e editor
+	switch name {
+	case "json":
+		e, err = newJSONEditor(config)
+	case "yaml":
+		e, err = newYAMLEditor(config)
+	case "toml":
+		e, err = newTOMLEditor(config)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, op := range ops {
+		if err := applyOperation(e, op); err != nil {
+			return "", err
+		}
+	}
+	return e.encode()
+}
+
+func applyOperation(e editor, op Operation) error {
+	path, err := splitKey(op.Key)
+	if err != nil && !(op.Op == OpMerge && op.Key == "") {
+		return err
+	}
+
+	switch op.Op {
+	case OpSet:
+		value, err := plain(op.Value)
+		if err != nil {
+			return err
+		}
+		return e.set(path, value)
+	case OpDelete:
+		return e.delete(path)
+	case OpMerge:
+		value, err := plain(op.Value)
+		if err != nil {
+			return err
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("[patch.Apply] merge of key '" + op.Key + "' needs a map value")
+		}
+		for _, leaf := range leaves(path, values) {
+			if err := e.set(leaf.path, leaf.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.New("[patch.Apply] unknown operation '" + string(op.Op) + "'")
+	}
+}
+
+func splitKey(key string) ([]string, error) {
+	if key == "" {
+		return nil, errors.New("[patch.Apply] the key can not be empty")
+	}
+	path := strings.Split(key, ".")
+	for _, segment := range path {
+		if segment == "" {
+			return nil, errors.New("[patch.Apply] invalid key '" + key + "'")
+		}
+	}
+	return path, nil
+}
+
+// plain converts structs, pointers and typed maps and slices to the values of decode.Marshal
+func plain(value interface{}) (interface{}, error) {
+	values, err := decode.Marshal(map[string]interface{}{"value": value})
+	if err != nil {
+		return nil, err
+	}
+	return values["value"], nil
+}
+
+type leaf struct {
+	path  []string
+	value interface{}
+}
+
+// leaves lists the non map values below path in key order, the values of a map come
+// before those of its nested maps and empty maps are leaves
+func leaves(path []string, values map[string]interface{}) []leaf {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result, nested := []leaf{}, []leaf{}
+	for _, key := range keys {
+		child := append(append([]string{}, path...), key)
+		if m, ok := values[key].(map[string]interface{}); ok && len(m) > 0 {
+			nested = append(nested, leaves(child, m)...)
+			continue
+		}
+		result = append(result, leaf{path: child, value: values[key]})
+	}
+	return append(result, nested...)
+}
+
+// detectIndent returns the indentation of the first indented line, or def
+func detectIndent(config string, def int) int {
+	for _, line := range strings.Split(config, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		return len(line) - len(trimmed)
+	}
+	return def
+}
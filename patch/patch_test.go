@@ -0,0 +1,143 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyKeepsLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		config string
+		ops    []Operation
+		want   string
+	}{
+		{
+			name:   "yaml set",
+			format: "yaml",
+			config: "# server\nserver:\n  port: 80 # http\n  host: a\nname: x\n",
+			ops:    []Operation{Set("server.port", 8080)},
+			want:   "# server\nserver:\n  port: 8080 # http\n  host: a\nname: x\n",
+		},
+		{
+			name:   "yaml delete",
+			format: "yaml",
+			config: "# server\nserver:\n  port: 80 # http\n  host: a\nname: x\n",
+			ops:    []Operation{Delete("server.host")},
+			want:   "# server\nserver:\n  port: 80 # http\nname: x\n",
+		},
+		{
+			name:   "yaml merge",
+			format: "yaml",
+			config: "# server\nserver:\n  port: 80 # http\n  host: a\nname: x\n",
+			ops:    []Operation{Merge("server", map[string]interface{}{"port": 81, "tls": true})},
+			want:   "# server\nserver:\n  port: 81 # http\n  host: a\n  tls: true\nname: x\n",
+		},
+		{
+			name:   "json set",
+			format: "json",
+			config: "{\n  \"b\": 1.50,\n  \"a\": {\n    \"y\": 1,\n    \"x\": 2\n  }\n}\n",
+			ops:    []Operation{Set("a.x", 3)},
+			want:   "{\n  \"b\": 1.50,\n  \"a\": {\n    \"y\": 1,\n    \"x\": 3\n  }\n}\n",
+		},
+		{
+			name:   "json delete",
+			format: "json",
+			config: "{\n  \"b\": 1.50,\n  \"a\": {\n    \"y\": 1,\n    \"x\": 2\n  }\n}\n",
+			ops:    []Operation{Delete("a.y")},
+			want:   "{\n  \"b\": 1.50,\n  \"a\": {\n    \"x\": 2\n  }\n}\n",
+		},
+		{
+			name:   "json merge",
+			format: "json",
+			config: "{\n  \"b\": 1.50,\n  \"a\": {\n    \"y\": 1,\n    \"x\": 2\n  }\n}\n",
+			ops:    []Operation{Merge("a", map[string]interface{}{"y": 5, "z": 6})},
+			want:   "{\n  \"b\": 1.50,\n  \"a\": {\n    \"y\": 5,\n    \"x\": 2,\n    \"z\": 6\n  }\n}\n",
+		},
+		{
+			name:   "toml set",
+			format: "toml",
+			config: "# top\nname = \"x\"\n\n# server\n[server]\nport = 80 # http\nhost = \"a\"\n",
+			ops:    []Operation{Set("server.port", 8080)},
+			want:   "# top\nname = \"x\"\n\n# server\n[server]\nport = 8080 # http\nhost = \"a\"\n",
+		},
+		{
+			name:   "toml delete",
+			format: "toml",
+			config: "# top\nname = \"x\"\n\n# server\n[server]\nport = 80 # http\nhost = \"a\"\n",
+			ops:    []Operation{Delete("server.host")},
+			want:   "# top\nname = \"x\"\n\n# server\n[server]\nport = 80 # http\n",
+		},
+		{
+			name:   "toml merge",
+			format: "toml",
+			config: "# top\nname = \"x\"\n\n# server\n[server]\nport = 80 # http\nhost = \"a\"\n",
+			ops:    []Operation{Merge("server", map[string]interface{}{"port": 81, "tls": true})},
+			want:   "# top\nname = \"x\"\n\n# server\n[server]\nport = 81 # http\nhost = \"a\"\ntls = true\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Apply(test.config, test.format, test.ops)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("Apply =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyRefusesEdits(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		config string
+		ops    []Operation
+		want   string
+	}{
+		{
+			name:   "toml array of tables",
+			format: "toml",
+			config: "# servers\n[[srv]]\nname = \"a\"\n\n[[srv]]\nname = \"b\"\n",
+			ops:    []Operation{Set("srv.1.name", "c")},
+			want:   "in place",
+		},
+		{
+			name:   "yaml set below alias",
+			format: "yaml",
+			config: "base: &base\n  port: 80\nserver: *base\n",
+			ops:    []Operation{Set("server.port", 81)},
+			want:   "alias",
+		},
+		{
+			name:   "yaml delete below alias",
+			format: "yaml",
+			config: "base: &base\n  port: 80\nserver: *base\n",
+			ops:    []Operation{Delete("server.port")},
+			want:   "alias",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Apply(test.config, test.format, test.ops)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("Apply = %q, %v, want an error with %q", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyReplacesAlias(t *testing.T) {
+	config := "base: &base\n  port: 80\nserver: *base\n"
+	got, err := Apply(config, "yaml", []Operation{Set("server", map[string]interface{}{"port": 81})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "base: &base\n  port: 80\nserver:\n  port: 81\n"
+	if got != want {
+		t.Fatalf("Apply =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,498 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"ecm-sdk-go/utils"
+
+	"github.com/BurntSushi/toml"
+)
+
+var errUnsupportedEdit = errors.New("edit does not fit the line structure of the document")
+
+// tomlEditor edits the toml source line by line and keeps the expected values next to it,
+// an edit which does not fit the source fails instead of re-encoding the document without its comments
+type tomlEditor struct {
+	lines  []string
+	values map[string]interface{}
+}
+
+func newTOMLEditor(config string) (*tomlEditor, error) {
+	values, err := utils.ParseConfig(config, "toml")
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(config, "\n"), "\n")
+	if strings.TrimSpace(config) == "" {
+		lines = nil
+	}
+	return &tomlEditor{lines: lines, values: values}, nil
+}
+
+func (e *tomlEditor) set(path []string, value interface{}) error {
+	if value == nil {
+		return errors.New("[patch.Apply] toml has no null value, key '" + strings.Join(path, ".") + "'")
+	}
+	if err := setValue(e.values, path, value); err != nil {
+		return err
+	}
+	if err := e.setLine(path, value); err != nil {
+		return tomlEditError(path, err)
+	}
+	return nil
+}
+
+func (e *tomlEditor) delete(path []string) error {
+	deleteValue(e.values, path)
+	if err := e.deleteLine(path); err != nil {
+		return tomlEditError(path, err)
+	}
+	return nil
+}
+
+func (e *tomlEditor) encode() (string, error) {
+	encoded, err := encodeTOML(e.values)
+	if err != nil {
+		return "", err
+	}
+
+	// the edited source must read back as the expected values, e.g. keys of arrays of tables
+	// are not found by the line edits
+	edited := strings.Join(e.lines, "\n") + "\n"
+	editedValues, err := utils.ParseConfig(edited, "toml")
+	if err != nil {
+		return "", errors.New("[patch.Apply] cannot patch the toml document in place: " + err.Error())
+	}
+	expectedValues, err := utils.ParseConfig(encoded, "toml")
+	if err != nil {
+		return "", err
+	}
+	if !reflect.DeepEqual(editedValues, expectedValues) {
+		return "", errors.New("[patch.Apply] cannot patch the toml document in place: " + errUnsupportedEdit.Error())
+	}
+	return edited, nil
+}
+
+func tomlEditError(path []string, err error) error {
+	return errors.New("[patch.Apply] cannot patch toml key '" + strings.Join(path, ".") + "' in place: " + err.Error())
+}
+
+func (e *tomlEditor) setLine(path []string, value interface{}) error {
+	key := strings.Join(path, ".")
+
+	if values, ok := value.(map[string]interface{}); ok {
+		if len(values) == 0 {
+			return errUnsupportedEdit
+		}
+		if err := e.deleteLine(path); err != nil {
+			return err
+		}
+		for _, leaf := range leaves(path, values) {
+			if err := e.setLine(leaf.path, leaf.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	encoded, err := tomlValue(value)
+	if err != nil {
+		return err
+	}
+
+	lines := scanTOML(e.lines)
+	for _, line := range lines {
+		if line.kind == tomlKey && line.key == key {
+			text := e.lines[line.index][:line.valueStart]
+			if !strings.HasSuffix(text, " ") && !strings.HasSuffix(text, "\t") {
+				text += " "
+			}
+			text += encoded
+			if line.comment >= 0 {
+				text += " " + e.lines[line.end][line.comment:]
+			}
+			e.lines = splice(e.lines, line.index, line.end+1, text)
+			return nil
+		}
+	}
+
+	// the key is a table today, replace it
+	for _, line := range lines {
+		if line.key == key || strings.HasPrefix(line.key, key+".") {
+			if err := e.deleteLine(path); err != nil {
+				return err
+			}
+			lines = scanTOML(e.lines)
+			break
+		}
+	}
+
+	// insert into the deepest table which holds the key directly
+	table := strings.Join(path[:len(path)-1], ".")
+	position, indent, found := tomlInsertPosition(e.lines, lines, table)
+	if found {
+		e.lines = splice(e.lines, position, position, indent+tomlKeyName(path[len(path)-1])+" = "+encoded)
+		return nil
+	}
+
+	// no such table, append one
+	if len(e.lines) > 0 && strings.TrimSpace(e.lines[len(e.lines)-1]) != "" {
+		e.lines = append(e.lines, "")
+	}
+	e.lines = append(e.lines, "["+table+"]", tomlKeyName(path[len(path)-1])+" = "+encoded)
+	return nil
+}
+
+func (e *tomlEditor) deleteLine(path []string) error {
+	key := strings.Join(path, ".")
+	matches := func(name string) bool {
+		return name == key || strings.HasPrefix(name, key+".")
+	}
+
+	lines := scanTOML(e.lines)
+	remove := make([]bool, len(e.lines))
+	for i, line := range lines {
+		switch line.kind {
+		case tomlKey:
+			if matches(line.key) {
+				for j := line.index; j <= line.end; j++ {
+					remove[j] = true
+				}
+			}
+		case tomlTable, tomlArrayTable:
+			if !matches(line.key) {
+				continue
+			}
+			// the header and its section up to the next header
+			end := len(e.lines)
+			for _, next := range lines[i+1:] {
+				if next.kind == tomlTable || next.kind == tomlArrayTable {
+					end = next.index
+					break
+				}
+			}
+			// with the comments right above the header
+			start := line.index
+			for start > 0 && strings.HasPrefix(strings.TrimSpace(e.lines[start-1]), "#") {
+				start--
+			}
+			for j := start; j < end; j++ {
+				remove[j] = true
+			}
+		}
+	}
+
+	kept := make([]string, 0, len(e.lines))
+	for i, text := range e.lines {
+		if !remove[i] {
+			kept = append(kept, text)
+		}
+	}
+	e.lines = kept
+	return nil
+}
+
+const (
+	tomlKey = iota
+	tomlTable
+	tomlArrayTable
+)
+
+// tomlLine is a key line or a table header of the source, a key may span the lines index to end
+type tomlLine struct {
+	kind       int
+	index      int
+	end        int
+	key        string
+	valueStart int
+	// comment is the offset of the trailing comment in the end line, -1 without comment
+	comment int
+}
+
+// scanTOML lists the keys with their full dotted name and the table headers of the source.
+// Keys of arrays of tables get a name no dotted key can match.
+func scanTOML(lines []string) []tomlLine {
+	result := []tomlLine{}
+	table := ""
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "[["):
+			end := strings.Index(trimmed, "]]")
+			if end < 0 {
+				continue
+			}
+			name := tomlKeyPath(trimmed[2:end])
+			result = append(result, tomlLine{kind: tomlArrayTable, index: i, end: i, key: name, comment: -1})
+			table = name + "[]"
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				continue
+			}
+			table = tomlKeyPath(trimmed[1:end])
+			result = append(result, tomlLine{kind: tomlTable, index: i, end: i, key: table, comment: -1})
+		default:
+			equal := tomlEqualIndex(lines[i])
+			if equal < 0 {
+				continue
+			}
+			valueStart := equal + 1
+			for valueStart < len(lines[i]) && (lines[i][valueStart] == ' ' || lines[i][valueStart] == '\t') {
+				valueStart++
+			}
+			key := tomlKeyPath(lines[i][:equal])
+			if table != "" {
+				key = table + "." + key
+			}
+			end, comment := scanTOMLValue(lines, i, valueStart)
+			result = append(result, tomlLine{kind: tomlKey, index: i, end: end, key: key, valueStart: valueStart, comment: comment})
+			i = end
+		}
+	}
+	return result
+}
+
+// scanTOMLValue finds the line where the value starting at lines[i][start] ends,
+// values span lines inside arrays, inline tables and multi-line strings
+func scanTOMLValue(lines []string, i, start int) (int, int) {
+	depth := 0
+	quote := ""
+	for j := i; j < len(lines); j++ {
+		line := lines[j]
+		k := 0
+		if j == i {
+			k = start
+		}
+		for ; k < len(line); k++ {
+			c := line[k]
+			switch {
+			case quote == `"""` || quote == `'''`:
+				if quote == `"""` && c == '\\' {
+					k++
+				} else if strings.HasPrefix(line[k:], quote) {
+					k += 2
+					quote = ""
+				}
+			case quote == `"`:
+				if c == '\\' {
+					k++
+				} else if c == '"' {
+					quote = ""
+				}
+			case quote == `'`:
+				if c == '\'' {
+					quote = ""
+				}
+			case strings.HasPrefix(line[k:], `"""`) || strings.HasPrefix(line[k:], `'''`):
+				quote = line[k : k+3]
+				k += 2
+			case c == '"' || c == '\'':
+				quote = string(c)
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				depth--
+			case c == '#':
+				if depth <= 0 {
+					return j, k
+				}
+				k = len(line)
+			}
+		}
+		if quote == `"` || quote == `'` {
+			quote = ""
+		}
+		if depth <= 0 && quote == "" {
+			return j, -1
+		}
+	}
+	return len(lines) - 1, -1
+}
+
+// tomlEqualIndex returns the offset of the = between the key and the value, -1 when the line is no key
+func tomlEqualIndex(line string) int {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		case c == '#':
+			return -1
+		}
+	}
+	return -1
+}
+
+// tomlKeyPath returns the dotted name of a key or a table header, "a . 'b'" is "a.b"
+func tomlKeyPath(key string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(key, ".") {
+		segment = strings.TrimSpace(segment)
+		if len(segment) >= 2 && (segment[0] == '"' || segment[0] == '\'') && segment[len(segment)-1] == segment[0] {
+			segment = segment[1 : len(segment)-1]
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+func tomlKeyName(key string) string {
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return strconv.Quote(key)
+		}
+	}
+	return key
+}
+
+// tomlInsertPosition returns where a key of the table is inserted, after the last key of its section
+func tomlInsertPosition(text []string, lines []tomlLine, table string) (int, string, bool) {
+	start, end, found := 0, len(text), table == ""
+	for _, line := range lines {
+		if line.kind == tomlKey {
+			continue
+		}
+		if found {
+			end = line.index
+			break
+		}
+		if line.kind == tomlTable && line.key == table {
+			start, found = line.index+1, true
+		}
+	}
+	if !found {
+		return 0, "", false
+	}
+
+	position, indent := -1, ""
+	for _, line := range lines {
+		if line.kind == tomlKey && line.index >= start && line.index < end {
+			position = line.end + 1
+			indent = text[line.index][:len(text[line.index])-len(strings.TrimLeft(text[line.index], " \t"))]
+		}
+	}
+	if position >= 0 {
+		return position, indent, true
+	}
+	if table != "" {
+		return start, "", true
+	}
+	// the comments above the first table belong to it
+	for end > 0 && (strings.TrimSpace(text[end-1]) == "" || strings.HasPrefix(strings.TrimSpace(text[end-1]), "#")) {
+		end--
+	}
+	return end, "", true
+}
+
+// tomlValue encodes a scalar or an array the way it appears after "key = "
+func tomlValue(value interface{}) (string, error) {
+	var buffer bytes.Buffer
+	if err := toml.NewEncoder(&buffer).Encode(map[string]interface{}{"v": value}); err != nil {
+		return "", err
+	}
+	encoded := strings.TrimSpace(buffer.String())
+	if !strings.HasPrefix(encoded, "v = ") || strings.Contains(encoded, "\n") {
+		return "", errUnsupportedEdit
+	}
+	return strings.TrimPrefix(encoded, "v = "), nil
+}
+
+func encodeTOML(values map[string]interface{}) (string, error) {
+	var buffer bytes.Buffer
+	encoder := toml.NewEncoder(&buffer)
+	encoder.Indent = "  "
+	if err := encoder.Encode(values); err != nil {
+		return "", errors.New("[patch.Apply] toml encode failed: " + err.Error())
+	}
+	return buffer.String(), nil
+}
+
+func splice(lines []string, from, to int, text ...string) []string {
+	result := make([]string, 0, len(lines)-(to-from)+len(text))
+	result = append(result, lines[:from]...)
+	result = append(result, text...)
+	return append(result, lines[to:]...)
+}
+
+// setValue sets the value below path in the nested maps, the list elements must exist
+func setValue(values map[string]interface{}, path []string, value interface{}) error {
+	var node interface{} = values
+	for i, segment := range path {
+		last := i == len(path)-1
+		switch container := node.(type) {
+		case map[string]interface{}:
+			if last {
+				container[segment] = value
+				return nil
+			}
+			child := container[segment]
+			switch child.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				child = map[string]interface{}{}
+				container[segment] = child
+			}
+			node = child
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(container) {
+				return errors.New("[patch.Apply] invalid list index in key '" + strings.Join(path, ".") + "'")
+			}
+			if last {
+				container[index] = value
+				return nil
+			}
+			child := container[index]
+			switch child.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				child = map[string]interface{}{}
+				container[index] = child
+			}
+			node = child
+		}
+	}
+	return nil
+}
+
+// deleteValue removes the key below path from the nested maps and lists and returns the edited node
+func deleteValue(node interface{}, path []string) interface{} {
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			return container
+		}
+		if len(path) == 1 {
+			delete(container, path[0])
+		} else {
+			container[path[0]] = deleteValue(child, path[1:])
+		}
+		return container
+	case []interface{}:
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 || index >= len(container) {
+			return container
+		}
+		if len(path) == 1 {
+			return append(container[:index:index], container[index+1:]...)
+		}
+		container[index] = deleteValue(container[index], path[1:])
+		return container
+	default:
+		return node
+	}
+}
@@ -0,0 +1,172 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type yamlEditor struct {
+	document yaml.Node
+	indent   int
+}
+
+func newYAMLEditor(config string) (*yamlEditor, error) {
+	e := &yamlEditor{indent: detectIndent(config, 2)}
+	if err := yaml.Unmarshal([]byte(config), &e.document); err != nil {
+		return nil, err
+	}
+	if e.document.Kind == 0 {
+		e.document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{newYAMLMapping()}}
+	}
+	if e.document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("[patch.Apply] the yaml document is not an object")
+	}
+	return e, nil
+}
+
+func (e *yamlEditor) set(path []string, value interface{}) error {
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+
+	var err error
+	node := e.document.Content[0]
+	for i, segment := range path {
+		last := i == len(path)-1
+		switch node.Kind {
+		case yaml.MappingNode:
+			index := yamlKeyIndex(node, segment)
+			if index < 0 {
+				child := newYAMLMapping()
+				if last {
+					child = valueNode
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment}, child)
+				node = child
+				continue
+			}
+			if last {
+				node.Content[index+1] = keepComments(node.Content[index+1], valueNode)
+				return nil
+			}
+			if node, err = yamlContainer(node, index+1, path[:i+1]); err != nil {
+				return err
+			}
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index > len(node.Content) {
+				return errors.New("[patch.Apply] invalid list index in key '" + strings.Join(path, ".") + "'")
+			}
+			if index == len(node.Content) {
+				// one past the end appends
+				child := newYAMLMapping()
+				if last {
+					child = valueNode
+				}
+				node.Content = append(node.Content, child)
+				node = child
+				continue
+			}
+			if last {
+				node.Content[index] = keepComments(node.Content[index], valueNode)
+				return nil
+			}
+			if node, err = yamlContainer(node, index, path[:i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *yamlEditor) delete(path []string) error {
+	node := e.document.Content[0]
+	for i, segment := range path {
+		last := i == len(path)-1
+		switch node.Kind {
+		case yaml.AliasNode:
+			return yamlAliasError(path[:i])
+		case yaml.MappingNode:
+			index := yamlKeyIndex(node, segment)
+			if index < 0 {
+				return nil
+			}
+			if last {
+				// the comment above the key belongs to the removed entry
+				node.Content = append(node.Content[:index], node.Content[index+2:]...)
+				return nil
+			}
+			node = node.Content[index+1]
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil
+			}
+			if last {
+				node.Content = append(node.Content[:index], node.Content[index+1:]...)
+				return nil
+			}
+			node = node.Content[index]
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (e *yamlEditor) encode() (string, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(e.indent)
+	if err := encoder.Encode(&e.document); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func newYAMLMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func yamlKeyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlContainer returns the child at index, replaced by an empty mapping when it is a scalar.
+// An alias is refused, editing below it would change the anchored node and every other alias of it.
+func yamlContainer(parent *yaml.Node, index int, path []string) (*yaml.Node, error) {
+	child := parent.Content[index]
+	if child.Kind == yaml.AliasNode {
+		return nil, yamlAliasError(path)
+	}
+	if child.Kind != yaml.MappingNode && child.Kind != yaml.SequenceNode {
+		child = keepComments(child, newYAMLMapping())
+		parent.Content[index] = child
+	}
+	return child, nil
+}
+
+func yamlAliasError(path []string) error {
+	return errors.New("[patch.Apply] key '" + strings.Join(path, ".") + "' is a yaml alias, edit its anchor instead")
+}
+
+// keepComments moves the comments of the replaced node to its replacement
+func keepComments(old, node *yaml.Node) *yaml.Node {
+	node.HeadComment = old.HeadComment
+	node.LineComment = old.LineComment
+	node.FootComment = old.FootComment
+	return node
+}
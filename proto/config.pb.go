@@ -178,6 +178,7 @@ type PublishConfigRequest struct {
 	Format               string   `protobuf:"bytes,4,opt,name=format,proto3" json:"format,omitempty"`
 	TagName              string   `protobuf:"bytes,5,opt,name=TagName,proto3" json:"TagName,omitempty"`
 	Description          string   `protobuf:"bytes,6,opt,name=Description,proto3" json:"Description,omitempty"`
+	ExpectedVersion      string   `protobuf:"bytes,7,opt,name=ExpectedVersion,proto3" json:"ExpectedVersion,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -249,6 +250,13 @@ func (m *PublishConfigRequest) GetDescription() string {
 	return ""
 }
 
+func (m *PublishConfigRequest) GetExpectedVersion() string {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return ""
+}
+
 type Response struct {
 	Result               string   `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -715,36 +723,37 @@ var _ConfigService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("config.proto", fileDescriptor_config_86edd62f907d0554) }
 
 var fileDescriptor_config_86edd62f907d0554 = []byte{
-	// 494 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x53, 0x4d, 0x6f, 0xd3, 0x40,
-	0x10, 0xed, 0xa6, 0x8d, 0xd3, 0x4c, 0x62, 0x11, 0xa6, 0x11, 0x58, 0x46, 0x42, 0xd5, 0x0a, 0xa4,
-	0x8a, 0x43, 0x85, 0xc2, 0x89, 0x03, 0x20, 0x20, 0xa2, 0x1c, 0x0a, 0x8a, 0xc2, 0xc7, 0x7d, 0x6b,
-	0xa6, 0xc1, 0x6a, 0x1a, 0x1b, 0xef, 0x3a, 0x12, 0x57, 0x4e, 0xdc, 0xf9, 0x4d, 0x48, 0x9c, 0xf9,
-	0x45, 0xc8, 0x3b, 0xbb, 0x21, 0x96, 0x13, 0x3e, 0xa4, 0x9e, 0x92, 0x79, 0x33, 0xf3, 0xe6, 0xbd,
-	0xf1, 0x2c, 0xf4, 0x93, 0x6c, 0x71, 0x9e, 0xce, 0x8e, 0xf3, 0x22, 0x33, 0x19, 0xb6, 0xed, 0x8f,
-	0xfc, 0x29, 0x20, 0x78, 0x6e, 0x71, 0x8c, 0xa0, 0xb3, 0xa4, 0x42, 0xa7, 0xd9, 0x22, 0x12, 0x87,
-	0xe2, 0xa8, 0x3b, 0xf5, 0x21, 0xde, 0x80, 0x20, 0x2f, 0xcf, 0xe6, 0x69, 0x12, 0xb5, 0x6c, 0xc2,
-	0x45, 0x55, 0x47, 0x5e, 0xa4, 0x4b, 0x65, 0x28, 0xda, 0xe5, 0x0e, 0x17, 0xe2, 0x1d, 0x08, 0xb9,
-	0xe6, 0xbd, 0x63, 0xdc, 0xb3, 0xf9, 0x3a, 0x58, 0xf1, 0x9e, 0x67, 0xc5, 0xa5, 0x32, 0x51, 0x9b,
-	0x79, 0x39, 0x42, 0x09, 0x7d, 0x2e, 0x7c, 0xc1, 0xd9, 0xc0, 0x66, 0x6b, 0x18, 0xc6, 0xb0, 0xaf,
-	0xa9, 0x58, 0xa6, 0x09, 0xe9, 0xa8, 0x63, 0xf3, 0xab, 0x58, 0x7e, 0x13, 0x10, 0xb2, 0x29, 0x3f,
-	0x69, 0xbb, 0x37, 0x09, 0xfd, 0xa7, 0x79, 0x7e, 0x52, 0x64, 0x65, 0xfe, 0x5a, 0x5d, 0x92, 0x73,
-	0x58, 0xc3, 0xf0, 0x36, 0x00, 0xd3, 0xd9, 0x0a, 0xb6, 0xba, 0x86, 0xfc, 0x9b, 0x5b, 0xf9, 0x43,
-	0xc0, 0x70, 0x52, 0x21, 0xfa, 0x23, 0xf7, 0x4e, 0xe9, 0x53, 0x49, 0xda, 0x34, 0x24, 0x88, 0xbf,
-	0x4a, 0x68, 0x35, 0x24, 0x6c, 0xff, 0x14, 0xbf, 0x97, 0xbc, 0x57, 0x5b, 0x72, 0x04, 0x9d, 0xb7,
-	0x8a, 0xe9, 0x78, 0xfb, 0x3e, 0xc4, 0x43, 0xe8, 0x8d, 0x49, 0x27, 0x45, 0x9a, 0x9b, 0xca, 0x0c,
-	0x6f, 0x7f, 0x1d, 0x92, 0x12, 0xf6, 0xa7, 0xa4, 0xf3, 0x6c, 0xa1, 0x2d, 0x7f, 0x41, 0xba, 0x9c,
-	0x1b, 0xa7, 0xdb, 0x45, 0xf2, 0x8b, 0x80, 0xc1, 0xa4, 0x34, 0x57, 0x6f, 0xf5, 0x1e, 0x0c, 0x5e,
-	0x92, 0x2a, 0xcc, 0x33, 0x52, 0x66, 0xa2, 0x92, 0x0b, 0x35, 0xf3, 0x9e, 0x1b, 0xb8, 0x7c, 0x04,
-	0x07, 0xef, 0xf2, 0x0f, 0xca, 0x10, 0xf7, 0xbf, 0x22, 0xad, 0xd5, 0x8c, 0x70, 0x00, 0xbb, 0x17,
-	0xf4, 0xd9, 0x4d, 0xaf, 0xfe, 0xe2, 0x10, 0xda, 0x4b, 0x35, 0x2f, 0xfd, 0x3c, 0x0e, 0xe4, 0x57,
-	0x01, 0xd7, 0xd7, 0x3c, 0x38, 0xc7, 0x77, 0x21, 0xe0, 0xa7, 0x64, 0x09, 0x7a, 0xa3, 0x90, 0x9f,
-	0xd4, 0xb1, 0x2b, 0x73, 0x49, 0x3c, 0x85, 0x83, 0xb2, 0x39, 0xdb, 0x0e, 0xe8, 0x8d, 0x62, 0xd7,
-	0xb3, 0x41, 0xdd, 0x74, 0x53, 0xdb, 0xe8, 0x7b, 0xcb, 0xdf, 0xf4, 0x1b, 0x3e, 0x73, 0x1c, 0x41,
-	0xf7, 0x84, 0x9c, 0x36, 0x1c, 0xd6, 0x34, 0xb8, 0x93, 0x8b, 0xeb, 0xca, 0xe4, 0x0e, 0x3e, 0x84,
-	0xfe, 0x69, 0xaa, 0x0d, 0x2d, 0xfe, 0xab, 0xed, 0x48, 0xdc, 0x17, 0xf8, 0x04, 0xc2, 0xda, 0xf5,
-	0xe2, 0x2d, 0x57, 0xb5, 0xe9, 0xa6, 0xe3, 0x6b, 0x2e, 0xe9, 0x97, 0x26, 0x77, 0x70, 0x0c, 0xdd,
-	0xd5, 0x2e, 0xf1, 0xe6, 0xaa, 0xb9, 0x7e, 0x21, 0x71, 0xd4, 0x4c, 0x78, 0x06, 0x2b, 0xe3, 0x31,
-	0x84, 0x63, 0x9a, 0x93, 0x21, 0xff, 0x2d, 0xff, 0xb0, 0xc9, 0x0d, 0x2a, 0xce, 0x02, 0x8b, 0x3c,
-	0xf8, 0x15, 0x00, 0x00, 0xff, 0xff, 0x4c, 0xe2, 0x86, 0xd7, 0x0e, 0x05, 0x00, 0x00,
+	// 508 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x53, 0xcb, 0x6e, 0x13, 0x31,
+	0x14, 0xad, 0xd3, 0x26, 0x69, 0x6e, 0x12, 0x35, 0xdc, 0x46, 0x30, 0x0a, 0x12, 0xaa, 0x2c, 0x90,
+	0x22, 0x16, 0x15, 0x0a, 0x2b, 0x16, 0x80, 0x80, 0x40, 0x59, 0x14, 0x14, 0x85, 0xc7, 0xde, 0x9d,
+	0xde, 0x86, 0x51, 0xd3, 0x8c, 0x19, 0x7b, 0x22, 0xd8, 0xb2, 0x62, 0xcf, 0x37, 0xf1, 0x03, 0x7c,
+	0x0a, 0x5f, 0x80, 0xc6, 0xd7, 0x0e, 0x19, 0x26, 0xe5, 0x21, 0xb1, 0x9a, 0xb9, 0xe7, 0xbe, 0xce,
+	0x39, 0xb6, 0xa1, 0x13, 0xa7, 0x8b, 0xb3, 0x64, 0x76, 0xa8, 0xb3, 0xd4, 0xa6, 0x58, 0x77, 0x1f,
+	0xf9, 0x4d, 0x40, 0xe3, 0x89, 0xc3, 0x31, 0x82, 0xe6, 0x92, 0x32, 0x93, 0xa4, 0x8b, 0x48, 0x1c,
+	0x88, 0x61, 0x6b, 0x1a, 0x42, 0xbc, 0x0a, 0x0d, 0x9d, 0x9f, 0xcc, 0x93, 0x38, 0xaa, 0xb9, 0x84,
+	0x8f, 0x8a, 0x0e, 0x9d, 0x25, 0x4b, 0x65, 0x29, 0xda, 0xe6, 0x0e, 0x1f, 0xe2, 0x4d, 0xe8, 0x72,
+	0xcd, 0x5b, 0x3f, 0x71, 0xc7, 0xe5, 0xcb, 0x60, 0x31, 0xf7, 0x2c, 0xcd, 0x2e, 0x94, 0x8d, 0xea,
+	0x3c, 0x97, 0x23, 0x94, 0xd0, 0xe1, 0xc2, 0x67, 0x9c, 0x6d, 0xb8, 0x6c, 0x09, 0xc3, 0x01, 0xec,
+	0x1a, 0xca, 0x96, 0x49, 0x4c, 0x26, 0x6a, 0xba, 0xfc, 0x2a, 0x96, 0x5f, 0x04, 0x74, 0x59, 0x54,
+	0xd8, 0x74, 0xb9, 0x36, 0x09, 0x9d, 0x47, 0x5a, 0x1f, 0x65, 0x69, 0xae, 0x5f, 0xaa, 0x0b, 0xf2,
+	0x0a, 0x4b, 0x18, 0xde, 0x00, 0xe0, 0x71, 0xae, 0x82, 0xa5, 0xae, 0x21, 0x7f, 0xa7, 0x56, 0x7e,
+	0x17, 0xd0, 0x9f, 0x14, 0x88, 0x79, 0xc7, 0xbd, 0x53, 0x7a, 0x9f, 0x93, 0xb1, 0x15, 0x0a, 0xe2,
+	0x8f, 0x14, 0x6a, 0x15, 0x0a, 0x97, 0x1f, 0xc5, 0x4f, 0x93, 0x77, 0x4a, 0x26, 0x47, 0xd0, 0x7c,
+	0xad, 0x78, 0x1c, 0xbb, 0x1f, 0x42, 0x3c, 0x80, 0xf6, 0x98, 0x4c, 0x9c, 0x25, 0xda, 0x16, 0x62,
+	0xd8, 0xfd, 0x75, 0x08, 0x87, 0xb0, 0xf7, 0xf4, 0x83, 0xa6, 0xd8, 0xd2, 0x69, 0x90, 0xcc, 0x67,
+	0xf0, 0x2b, 0x2c, 0x25, 0xec, 0x4e, 0xc9, 0xe8, 0x74, 0x61, 0x1c, 0x93, 0x8c, 0x4c, 0x3e, 0xb7,
+	0x5e, 0xa1, 0x8f, 0xe4, 0x27, 0x01, 0xbd, 0x49, 0x6e, 0xff, 0xbf, 0x29, 0xb7, 0xa1, 0xf7, 0x9c,
+	0x54, 0x66, 0x1f, 0x93, 0xb2, 0x13, 0x15, 0x9f, 0xab, 0x59, 0x70, 0xa7, 0x82, 0xcb, 0xfb, 0xb0,
+	0xff, 0x46, 0x9f, 0x2a, 0x4b, 0xdc, 0xff, 0x82, 0x8c, 0x51, 0x33, 0xc2, 0x1e, 0x6c, 0x9f, 0xd3,
+	0x47, 0xbf, 0xbd, 0xf8, 0xc5, 0x3e, 0xd4, 0x97, 0x6a, 0x9e, 0x87, 0x7d, 0x1c, 0xc8, 0xcf, 0x02,
+	0xae, 0xac, 0x69, 0xf0, 0x8a, 0x6f, 0x41, 0x83, 0x1f, 0x9d, 0x1b, 0xd0, 0x1e, 0x75, 0xf9, 0xf1,
+	0x1d, 0xfa, 0x32, 0x9f, 0xc4, 0x63, 0xd8, 0xcf, 0xab, 0xbb, 0xdd, 0x82, 0xf6, 0x68, 0xe0, 0x7b,
+	0x36, 0xb0, 0x9b, 0x6e, 0x6a, 0x1b, 0x7d, 0xad, 0x85, 0xdb, 0xff, 0x8a, 0x1f, 0x04, 0x8e, 0xa0,
+	0x75, 0x44, 0x9e, 0x1b, 0xf6, 0x4b, 0x1c, 0xfc, 0x39, 0x0d, 0xca, 0xcc, 0xe4, 0x16, 0xde, 0x83,
+	0xce, 0x71, 0x62, 0x2c, 0x2d, 0xfe, 0xa9, 0x6d, 0x28, 0xee, 0x08, 0x7c, 0x08, 0xdd, 0xd2, 0x3d,
+	0xc7, 0xeb, 0xbe, 0x6a, 0xd3, 0xed, 0x1f, 0xec, 0xf9, 0x64, 0x30, 0x4d, 0x6e, 0xe1, 0x18, 0x5a,
+	0x2b, 0x2f, 0xf1, 0xda, 0xaa, 0xb9, 0x7c, 0x43, 0x06, 0x51, 0x35, 0x11, 0x26, 0x38, 0x1a, 0x0f,
+	0xa0, 0x3b, 0xa6, 0x39, 0x59, 0x0a, 0x67, 0xf9, 0x1b, 0x27, 0x37, 0xb0, 0x38, 0x69, 0x38, 0xe4,
+	0xee, 0x8f, 0x01, 0x00, 0x5d, 0xb3, 0xdd, 0xbc, 0x38, 0x05, 0x00, 0x00,
 }
@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"ecm-sdk-go/utils"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ValidationError lists the violations of the schema
+type ValidationError struct {
+	AppGroupName string
+	ConfigName   string
+	Errors       []string
+}
+
+func (e *ValidationError) Error() string {
+	return "[schema.Validate] config '" + e.AppGroupName + "/" + e.ConfigName + "' does not match the schema: " + strings.Join(e.Errors, "; ")
+}
+
+// Registry keeps the json schema of the private object of every app group and config
+type Registry struct {
+	mutex   sync.RWMutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+func NewRegistry() *Registry {
+	return &Registry{schemas: map[string]*gojsonschema.Schema{}}
+}
+
+// Register compiles the json schema and uses it for the app group and config
+func (registry *Registry) Register(appGroupName, configName, schema string) error {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return errors.New("[schema.Register] invalid json schema: " + err.Error())
+	}
+
+	registry.mutex.Lock()
+	registry.schemas[utils.GetServiceConfigKey(appGroupName, configName)] = compiled
+	registry.mutex.Unlock()
+	return nil
+}
+
+func (registry *Registry) Unregister(appGroupName, configName string) {
+	registry.mutex.Lock()
+	delete(registry.schemas, utils.GetServiceConfigKey(appGroupName, configName))
+	registry.mutex.Unlock()
+}
+
+// Validate checks the private content against the registered schema,
+// configs without schema and empty content always pass
+func (registry *Registry) Validate(appGroupName, configName, content, format string) error {
+	registry.mutex.RLock()
+	compiled := registry.schemas[utils.GetServiceConfigKey(appGroupName, configName)]
+	registry.mutex.RUnlock()
+
+	if compiled == nil || content == "" {
+		return nil
+	}
+
+	document, err := utils.ParseConfig(content, format)
+	if err != nil {
+		return &ValidationError{AppGroupName: appGroupName, ConfigName: configName, Errors: []string{"parse failed: " + err.Error()}}
+	}
+
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		return &ValidationError{AppGroupName: appGroupName, ConfigName: configName, Errors: []string{err.Error()}}
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	validationError := &ValidationError{AppGroupName: appGroupName, ConfigName: configName}
+	for _, resultError := range result.Errors() {
+		validationError.Errors = append(validationError.Errors, resultError.String())
+	}
+	return validationError
+}
@@ -0,0 +1,160 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// EncryptedPrefix and EncryptedSuffix wrap an encrypted value, e.g. ENC[base64]
+	EncryptedPrefix = "ENC["
+	EncryptedSuffix = "]"
+)
+
+// Decrypter decrypts the payload inside ENC[...]
+type Decrypter interface {
+	Decrypt(payload string) (string, error)
+}
+
+// Encrypter produces the payload put inside ENC[...]
+type Encrypter interface {
+	Encrypt(plaintext string) (string, error)
+}
+
+// IsEncrypted reports whether the value uses the ENC[...] convention
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix) && strings.HasSuffix(value, EncryptedSuffix)
+}
+
+// Wrap encrypts the plaintext and wraps it as ENC[...]
+func Wrap(encrypter Encrypter, plaintext string) (string, error) {
+	payload, err := encrypter.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return EncryptedPrefix + payload + EncryptedSuffix, nil
+}
+
+// Unwrap decrypts an ENC[...] value, other values are returned unchanged
+func Unwrap(decrypter Decrypter, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	return decrypter.Decrypt(value[len(EncryptedPrefix) : len(value)-len(EncryptedSuffix)])
+}
+
+// DecryptValues returns a copy of the flattened object with every ENC[...] string decrypted
+func DecryptValues(values map[string]interface{}, decrypter Decrypter) (map[string]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok && IsEncrypted(s) {
+			plaintext, err := Unwrap(decrypter, s)
+			if err != nil {
+				return nil, errors.New("[secret.DecryptValues] decrypt key '" + key + "' failed: " + err.Error())
+			}
+			value = plaintext
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// AESGCM encrypts with AES-GCM, the payload is base64 of the nonce followed by the sealed value
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM creates an AESGCM from a 16, 24 or 32 byte key
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{aead: aead}, nil
+}
+
+// NewAESGCMFromKeyFile reads a base64 encoded key from the file
+func NewAESGCMFromKeyFile(keyFile string) (*AESGCM, error) {
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseKey(string(content))
+	if err != nil {
+		return nil, errors.New("[secret.NewAESGCMFromKeyFile] " + keyFile + ": " + err.Error())
+	}
+	return NewAESGCM(key)
+}
+
+// ParseKey decodes a base64 encoded key
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("the key is not base64 encoded")
+	}
+	return key, nil
+}
+
+// GenerateKey returns a random base64 encoded 32 byte key
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func (a *AESGCM) Encrypt(plaintext string) (string, error) {
+	sealed, err := a.Seal([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (a *AESGCM) Decrypt(payload string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", errors.New("the payload is not base64 encoded")
+	}
+	plaintext, err := a.Open(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Seal encrypts the bytes, the nonce is prepended to the result
+func (a *AESGCM) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts bytes produced by Seal
+func (a *AESGCM) Open(sealed []byte) ([]byte, error) {
+	if len(sealed) < a.aead.NonceSize() {
+		return nil, errors.New("the payload is too short")
+	}
+	nonce := sealed[:a.aead.NonceSize()]
+	plaintext, err := a.aead.Open(nil, nonce, sealed[a.aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("decrypt failed, wrong key or corrupted payload")
+	}
+	return plaintext, nil
+}
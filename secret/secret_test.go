@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrapUnwrap(t *testing.T) {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := ioutil.WriteFile(keyFile, []byte(encoded+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := NewAESGCMFromKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped, err := Wrap(cipher, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(wrapped) {
+		t.Fatalf("%s is not wrapped", wrapped)
+	}
+	plaintext, err := Unwrap(cipher, wrapped)
+	if err != nil || plaintext != "s3cret" {
+		t.Fatalf("Unwrap = %q, %v", plaintext, err)
+	}
+	if plain, err := Unwrap(cipher, "plain"); err != nil || plain != "plain" {
+		t.Fatalf("Unwrap of a plain value = %q, %v", plain, err)
+	}
+}
+
+func TestDecryptValuesWrongKey(t *testing.T) {
+	key, _ := GenerateKey()
+	otherKey, _ := GenerateKey()
+	encrypter := newTestCipher(t, key)
+	decrypter := newTestCipher(t, otherKey)
+
+	wrapped, err := Wrap(encrypter, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{"password": wrapped, "port": 5432}
+
+	if _, err := DecryptValues(values, decrypter); err == nil {
+		t.Fatal("decrypted with the wrong key")
+	}
+	decrypted, err := DecryptValues(values, encrypter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted["password"] != "s3cret" || decrypted["port"] != 5432 || values["password"] != wrapped {
+		t.Fatalf("decrypted = %v, values = %v", decrypted, values)
+	}
+}
+
+func newTestCipher(t *testing.T, encoded string) *AESGCM {
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := NewAESGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"ecm-sdk-go/constants"
+	"errors"
+)
+
+// DefaultPrecedence lets private override public, and public override services
+var DefaultPrecedence = []string{constants.PrivateObjectName, constants.PublicObjectName, constants.ServicesObjectName}
+
+// EffectiveConfig merges the public, private and services objects into one key space
+type EffectiveConfig struct {
+	Values        map[string]interface{} `json:"values"`
+	Sources       map[string]string      `json:"sources"`
+	Version       string                 `json:"version"`
+	PublicVersion string                 `json:"publicVersion"`
+}
+
+// NewEffectiveConfig merges the objects of the key value config, precedence lists the objects
+// from the highest to the lowest, objects left out are not merged. Without precedence DefaultPrecedence is used.
+func NewEffectiveConfig(config *KeyValueConfig, precedence ...string) (*EffectiveConfig, error) {
+	if len(precedence) == 0 {
+		precedence = DefaultPrecedence
+	}
+
+	effectiveConfig := &EffectiveConfig{
+		Values:  map[string]interface{}{},
+		Sources: map[string]string{},
+	}
+	if config == nil {
+		return effectiveConfig, nil
+	}
+	effectiveConfig.Version = config.Version
+	effectiveConfig.PublicVersion = config.PublicVersion
+
+	seen := map[string]bool{}
+	for _, object := range precedence {
+		if seen[object] {
+			return nil, errors.New("[types.NewEffectiveConfig] object '" + object + "' is listed twice")
+		}
+		seen[object] = true
+
+		values, err := config.Object(object)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			if _, ok := effectiveConfig.Values[key]; ok {
+				continue
+			}
+			effectiveConfig.Values[key] = value
+			effectiveConfig.Sources[key] = object
+		}
+	}
+
+	return effectiveConfig, nil
+}
+
+// Object returns the flattened public, private or services object
+func (config *KeyValueConfig) Object(object string) (map[string]interface{}, error) {
+	switch object {
+	case constants.PrivateObjectName:
+		return config.Private, nil
+	case constants.PublicObjectName:
+		return config.Public, nil
+	case constants.ServicesObjectName:
+		return config.Services, nil
+	default:
+		return nil, errors.New("[types.KeyValueConfig] unknown object '" + object + "'")
+	}
+}
+
+// Get returns the resolved value of the key and the object it came from
+func (config *EffectiveConfig) Get(key string) (interface{}, string, bool) {
+	value, ok := config.Values[key]
+	if !ok {
+		return nil, "", false
+	}
+	return value, config.Sources[key], true
+}
+
+// Source returns the object the key was resolved from, empty when the key is not set
+func (config *EffectiveConfig) Source(key string) string {
+	return config.Sources[key]
+}
+
+// View returns a typed view over the merged values
+func (config *EffectiveConfig) View() *KeyValueView {
+	return &KeyValueView{objects: []map[string]interface{}{config.Values}}
+}
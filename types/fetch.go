@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+const (
+	// SourceServer marks a config returned by the server
+	SourceServer = "server"
+	// SourceCache marks a config read from the cache because the server could not be reached
+	SourceCache = "cache"
+)
+
+// FetchInfo tells where the last read of a config came from and when it was fetched from the server
+type FetchInfo struct {
+	Source string `json:"source"`
+	// FetchedAt is zero when the cache does not tell the fetch time
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Age is the time since the config was fetched from the server, 0 when the fetch time is unknown
+func (info FetchInfo) Age() time.Duration {
+	if info.FetchedAt.IsZero() {
+		return 0
+	}
+	return time.Since(info.FetchedAt)
+}
@@ -0,0 +1,213 @@
+package types
+
+import (
+	"ecm-sdk-go/decode"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyValueView reads typed values from a KeyValueConfig or an EffectiveConfig.
+// The GetX methods return the zero value when the key is missing or can not be converted,
+// the GetXOr methods return the given default instead.
+type KeyValueView struct {
+	objects []map[string]interface{}
+}
+
+// NewKeyValueView creates a view over flattened objects, earlier objects take precedence
+func NewKeyValueView(objects ...map[string]interface{}) *KeyValueView {
+	return &KeyValueView{objects: objects}
+}
+
+// View returns a typed view over the key value config merged with DefaultPrecedence
+func (config *KeyValueConfig) View() *KeyValueView {
+	effectiveConfig, _ := NewEffectiveConfig(config)
+	return effectiveConfig.View()
+}
+
+// Get returns the raw value decoded from the config
+func (view *KeyValueView) Get(key string) (interface{}, bool) {
+	for _, object := range view.objects {
+		if value, ok := object[key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// IsSet reports whether the key, or a key nested below it, is in the config
+func (view *KeyValueView) IsSet(key string) bool {
+	if _, ok := view.Get(key); ok {
+		return true
+	}
+	return len(view.children(key)) > 0
+}
+
+func (view *KeyValueView) GetString(key string) string {
+	return view.GetStringOr(key, "")
+}
+
+func (view *KeyValueView) GetStringOr(key string, defaultValue string) string {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	return decode.ToString(value)
+}
+
+func (view *KeyValueView) GetInt(key string) int {
+	return view.GetIntOr(key, 0)
+}
+
+func (view *KeyValueView) GetIntOr(key string, defaultValue int) int {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	i, err := decode.ToInt64(value)
+	if err != nil || int64(int(i)) != i {
+		return defaultValue
+	}
+	return int(i)
+}
+
+func (view *KeyValueView) GetInt64(key string) int64 {
+	return view.GetInt64Or(key, 0)
+}
+
+func (view *KeyValueView) GetInt64Or(key string, defaultValue int64) int64 {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	i, err := decode.ToInt64(value)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+func (view *KeyValueView) GetFloat(key string) float64 {
+	return view.GetFloatOr(key, 0)
+}
+
+func (view *KeyValueView) GetFloatOr(key string, defaultValue float64) float64 {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	f, err := decode.ToFloat64(value)
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
+func (view *KeyValueView) GetBool(key string) bool {
+	return view.GetBoolOr(key, false)
+}
+
+func (view *KeyValueView) GetBoolOr(key string, defaultValue bool) bool {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	b, err := decode.ToBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return b
+}
+
+// GetDuration reads strings such as "1m30s", numbers are nanoseconds
+func (view *KeyValueView) GetDuration(key string) time.Duration {
+	return view.GetDurationOr(key, 0)
+}
+
+func (view *KeyValueView) GetDurationOr(key string, defaultValue time.Duration) time.Duration {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	d, err := decode.ToDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// GetTime reads RFC 3339 strings, numbers are unix seconds
+func (view *KeyValueView) GetTime(key string) time.Time {
+	return view.GetTimeOr(key, time.Time{})
+}
+
+func (view *KeyValueView) GetTimeOr(key string, defaultValue time.Time) time.Time {
+	value, ok := view.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	t, err := decode.ToTime(value)
+	if err != nil {
+		return defaultValue
+	}
+	return t
+}
+
+// GetStringSlice reads a list flattened to "key.0", "key.1" ... or a comma separated string
+func (view *KeyValueView) GetStringSlice(key string) []string {
+	return view.GetStringSliceOr(key, nil)
+}
+
+func (view *KeyValueView) GetStringSliceOr(key string, defaultValue []string) []string {
+	if value, ok := view.Get(key); ok {
+		return decode.ToStringSlice(decode.ToString(value))
+	}
+
+	children := view.children(key)
+	if len(children) == 0 {
+		return defaultValue
+	}
+	indexes := []int{}
+	for child := range children {
+		index, err := strconv.Atoi(child)
+		if err != nil || strings.Contains(child, ".") {
+			return defaultValue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	result := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		result = append(result, decode.ToString(children[strconv.Itoa(index)]))
+	}
+	return result
+}
+
+// GetStringMap returns the values nested below key, keyed by the rest of the flattened key
+func (view *KeyValueView) GetStringMap(key string) map[string]interface{} {
+	return view.GetStringMapOr(key, nil)
+}
+
+func (view *KeyValueView) GetStringMapOr(key string, defaultValue map[string]interface{}) map[string]interface{} {
+	children := view.children(key)
+	if len(children) == 0 {
+		return defaultValue
+	}
+	return children
+}
+
+// children collects the entries below key, earlier objects take precedence
+func (view *KeyValueView) children(key string) map[string]interface{} {
+	keyPrefix := key + "."
+	result := map[string]interface{}{}
+	for i := len(view.objects) - 1; i >= 0; i-- {
+		for k, value := range view.objects[i] {
+			if strings.HasPrefix(k, keyPrefix) {
+				result[k[len(keyPrefix):]] = value
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffContextLines is the number of unchanged lines around the changes of a unified diff hunk
+const DiffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns the line diff of the two texts in the unified format, empty when they are equal
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var builder strings.Builder
+	builder.WriteString("--- " + oldName + "\n")
+	builder.WriteString("+++ " + newName + "\n")
+
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// skip to the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+			oldLine++
+			newLine++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// a hunk ends after more than twice the context lines are unchanged
+		end, unchanged := start, 0
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind == ' ' {
+				unchanged++
+				if unchanged > 2*DiffContextLines {
+					break
+				}
+				continue
+			}
+			unchanged = 0
+			end = i + 1
+		}
+
+		before := start - DiffContextLines
+		if before < 0 {
+			before = 0
+		}
+		after := end + DiffContextLines
+		if after > len(ops) {
+			after = len(ops)
+		}
+
+		hunkOld, hunkNew := oldLine-(start-before), newLine-(start-before)
+		oldCount, newCount := 0, 0
+		var hunk strings.Builder
+		for _, op := range ops[before:after] {
+			hunk.WriteString(string(op.kind) + op.text + "\n")
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		builder.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount)))
+		builder.WriteString(hunk.String())
+
+		for _, op := range ops[start:after] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		start = after
+	}
+	return builder.String()
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		line--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes the shortest edit script with the Myers algorithm
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	trace := [][]int{}
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset, d)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, offset, d int) []diffOp {
+	ops := []diffOp{}
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', text: a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: '+', text: b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: '-', text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{kind: ' ', text: a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var formatAliases = map[string]string{
+	"json": "json",
+	"yaml": "yaml",
+	"yml":  "yaml",
+	"toml": "toml",
+}
+
+var (
+	yamlLinePattern = regexp.MustCompile(`line (\d+):\s*`)
+	tomlLinePattern = regexp.MustCompile(`[Nn]ear line (\d+)(?: \([^)]*\))?:\s*`)
+)
+
+// ParseError reports a syntax error of a config document, Line and Column start at 1
+// and are 0 when the parser does not tell them. Only json errors have a Column, the yaml
+// and toml parsers report the line of the error but not its column.
+type ParseError struct {
+	Format  string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("[utils.ValidateConfig] invalid %s at line %d, column %d: %s", e.Format, e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("[utils.ValidateConfig] invalid %s at line %d: %s", e.Format, e.Line, e.Message)
+	default:
+		return fmt.Sprintf("[utils.ValidateConfig] invalid %s: %s", e.Format, e.Message)
+	}
+}
+
+// NormalizeFormat returns the canonical name of a config format, e.g. "YML" is "yaml"
+func NormalizeFormat(format string) (string, error) {
+	if name, ok := formatAliases[strings.ToLower(strings.TrimSpace(format))]; ok {
+		return name, nil
+	}
+	return "", errors.New("unsupported format '" + format + "'")
+}
+
+// formatName is NormalizeFormat for the parsers, unknown formats are kept and rejected by the caller
+func formatName(format string) string {
+	if name, err := NormalizeFormat(format); err == nil {
+		return name
+	}
+	return format
+}
+
+// ValidateConfig checks the syntax of the config, the document must be an object.
+// Syntax errors are returned as *ParseError, with the line and column for json and the line for yaml and toml.
+func ValidateConfig(config, format string) error {
+	name, err := NormalizeFormat(format)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(config) == "" {
+		return nil
+	}
+
+	var mapConfig map[string]interface{}
+	switch name {
+	case "json":
+		err = json.Unmarshal([]byte(config), &mapConfig)
+	case "yaml":
+		err = yaml.Unmarshal([]byte(config), &mapConfig)
+	case "toml":
+		err = toml.Unmarshal([]byte(config), &mapConfig)
+	}
+	if err != nil {
+		return newParseError(name, config, err)
+	}
+	return nil
+}
+
+// CanonicalizeConfig validates the config and rewrites it with sorted keys and two space indentation,
+// a yaml document with anchors or aliases is returned as written
+func CanonicalizeConfig(config, format string) (string, error) {
+	if err := ValidateConfig(config, format); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(config) == "" {
+		return config, nil
+	}
+
+	var buffer bytes.Buffer
+	switch formatName(format) {
+	case "json":
+		// keep the numbers as written, float64 would round large integers
+		var mapConfig map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(config))
+		decoder.UseNumber()
+		if err := decoder.Decode(&mapConfig); err != nil {
+			return "", err
+		}
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(mapConfig); err != nil {
+			return "", err
+		}
+	case "yaml":
+		// sort the yaml nodes, decoding into values would rewrite scalars such as y or 010
+		var document yamlv3.Node
+		if err := yamlv3.Unmarshal([]byte(config), &document); err != nil {
+			return "", err
+		}
+		// sorting could move an alias before its anchor and the encoder rewrites merge keys, keep such documents as written
+		if hasYAMLAnchors(&document) {
+			return config, nil
+		}
+		sortYAMLNode(&document)
+		encoder := yamlv3.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&document); err != nil {
+			return "", err
+		}
+		encoder.Close()
+	case "toml":
+		var mapConfig map[string]interface{}
+		if err := toml.Unmarshal([]byte(config), &mapConfig); err != nil {
+			return "", err
+		}
+		encoder := toml.NewEncoder(&buffer)
+		encoder.Indent = "  "
+		if err := encoder.Encode(mapConfig); err != nil {
+			return "", err
+		}
+	}
+	return buffer.String(), nil
+}
+
+// EncodeConfig writes nested maps, as returned by decode.Marshal, in the format
+func EncodeConfig(values map[string]interface{}, format string) (string, error) {
+	name, err := NormalizeFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	switch name {
+	case "json":
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(values)
+	case "yaml":
+		var content []byte
+		if content, err = yaml.Marshal(values); err == nil {
+			buffer.Write(content)
+		}
+	case "toml":
+		encoder := toml.NewEncoder(&buffer)
+		encoder.Indent = "  "
+		err = encoder.Encode(values)
+	}
+	if err != nil {
+		return "", fmt.Errorf("[utils.EncodeConfig] %s encode failed: %s", name, err.Error())
+	}
+	return buffer.String(), nil
+}
+
+// sortYAMLNode sorts the keys of every mapping of the yaml document
+func sortYAMLNode(node *yamlv3.Node) {
+	for _, child := range node.Content {
+		sortYAMLNode(child)
+	}
+	if node.Kind != yamlv3.MappingNode {
+		return
+	}
+
+	pairs := make([][2]*yamlv3.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yamlv3.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	for i, pair := range pairs {
+		node.Content[2*i], node.Content[2*i+1] = pair[0], pair[1]
+	}
+}
+
+// hasYAMLAnchors reports whether a node of the yaml document defines an anchor or is an alias
+func hasYAMLAnchors(node *yamlv3.Node) bool {
+	if node.Anchor != "" || node.Kind == yamlv3.AliasNode {
+		return true
+	}
+	for _, child := range node.Content {
+		if hasYAMLAnchors(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func newParseError(format, config string, err error) *ParseError {
+	parseError := &ParseError{Format: format, Message: err.Error()}
+
+	switch format {
+	case "json":
+		var offset int64 = -1
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			offset = e.Offset
+		case *json.UnmarshalTypeError:
+			offset = e.Offset
+		}
+		if offset >= 0 {
+			parseError.Line, parseError.Column = lineColumn(config, offset)
+		}
+	case "yaml":
+		message := strings.TrimPrefix(parseError.Message, "yaml: ")
+		if match := yamlLinePattern.FindStringSubmatchIndex(message); match != nil {
+			parseError.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+			message = strings.TrimSpace(message[:match[0]] + message[match[1]:])
+		}
+		parseError.Message = strings.Join(strings.Fields(message), " ")
+	case "toml":
+		message := parseError.Message
+		if match := tomlLinePattern.FindStringSubmatchIndex(message); match != nil {
+			parseError.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+			message = strings.TrimSpace(message[:match[0]] + message[match[1]:])
+		}
+		parseError.Message = message
+	}
+	return parseError
+}
+
+// lineColumn converts the byte offset reported by encoding/json into a line and a column
+func lineColumn(config string, offset int64) (int, int) {
+	if offset > int64(len(config)) {
+		offset = int64(len(config))
+	}
+	before := config[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - (strings.LastIndex(before, "\n") + 1)
+	if column < 1 {
+		column = 1
+	}
+	return line, column
+}
@@ -0,0 +1,51 @@
+package utils
+
+import "testing"
+
+func TestCanonicalizeConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		format string
+		want   string
+	}{
+		{"yaml sorted", "b: 1\na:\n    d: y\n    c: 010\n", "yaml", "a:\n  c: 010\n  d: y\nb: 1\n"},
+		{"yaml anchors keep order", "z: &base\n  x: 1\na:\n  <<: *base\n  y: 2\n", "yaml", "z: &base\n  x: 1\na:\n  <<: *base\n  y: 2\n"},
+		{"json", `{"b": 12345678901234567890, "a": true}`, "json", "{\n  \"a\": true,\n  \"b\": 12345678901234567890\n}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CanonicalizeConfig(test.config, test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("CanonicalizeConfig = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigPosition(t *testing.T) {
+	tests := []struct {
+		format       string
+		config       string
+		line, column int
+	}{
+		{"json", "{\n  \"a\": 1,\n  \"b\" 2\n}", 3, 7},
+		{"yaml", "a: 1\nb: [1, 2\nc: 3\n", 2, 0},
+		{"toml", "a = 1\nb = = 2\n", 2, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			err := ValidateConfig(test.config, test.format)
+			parseError, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("ValidateConfig = %v, want a *ParseError", err)
+			}
+			if parseError.Line != test.line || parseError.Column != test.column {
+				t.Fatalf("position = %d:%d, want %d:%d (%s)", parseError.Line, parseError.Column, test.line, test.column, parseError.Error())
+			}
+		})
+	}
+}
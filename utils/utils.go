@@ -1,212 +1,300 @@
-package utils
-
-import (
-	"ecm-sdk-go/constants"
-	"ecm-sdk-go/flatten"
-	configproto "ecm-sdk-go/proto"
-	"ecm-sdk-go/types"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"math/rand"
-	"strings"
-	"time"
-
-	"github.com/BurntSushi/toml"
-	"gopkg.in/yaml.v2"
-)
-
-func GetDefaultAppGroupName() (string, error) {
-	maxRetryTimes := 3
-
-	backendInfo, err := getBackendInfo(maxRetryTimes)
-	if err != nil {
-		return "", err
-	}
-	appGroupName := backendInfo.AppGroupConfig.AppGroupName
-
-	return appGroupName, nil
-}
-
-func GetDefaultConfigNames() ([]string, error) {
-	configNames := []string{}
-	maxRetryTimes := 3
-
-	backendInfo, err := getBackendInfo(maxRetryTimes)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, configs := range backendInfo.AppGroupConfig.Configs {
-		configNames = append(configNames, configs.ConfigName)
-	}
-
-	return configNames, nil
-}
-
-func ParseBackendInfo(maxRetryTimes int) (string, string, string, error) {
-
-	backendInfo := &struct {
-		ServiceName string `json:"serviceName"`
-		BackendName string `json:"backendName"`
-		Token       string `json:"token"`
-	}{}
-
-	// wait backend register
-	var content []byte
-	var err error
-	fileName := constants.BackendRegisterInfoPath
-	for i := 0; i < maxRetryTimes; i++ {
-		content, err = ioutil.ReadFile(fileName)
-		if err != nil {
-			if i == maxRetryTimes-1 {
-				return "", "", "", fmt.Errorf("failed to parse backend information from file:%s, err:%s! ", fileName, err.Error())
-			}
-			time.Sleep(time.Second)
-			continue
-		} else {
-			break
-		}
-	}
-
-	if err = json.Unmarshal(content, backendInfo); err != nil {
-		return "", "", "", err
-	}
-	return backendInfo.ServiceName, backendInfo.BackendName, backendInfo.Token, nil
-}
-
-func getBackendInfo(maxRetryTimes int) (*types.BackendRegisterResult, error) {
-
-	backendInfo := &types.BackendRegisterResult{}
-
-	// wait backend register
-	var content []byte
-	var err error
-	fileName := constants.BackendRegisterInfoPath
-	for i := 0; i < maxRetryTimes; i++ {
-		content, err = ioutil.ReadFile(fileName)
-		if err != nil {
-			if i == maxRetryTimes-1 {
-				return nil, fmt.Errorf("failed to parse backend information from file:%s, err:%s! ", fileName, err.Error())
-			}
-			time.Sleep(time.Second)
-			continue
-		} else {
-			break
-		}
-	}
-
-	if err = json.Unmarshal(content, backendInfo); err != nil {
-		return nil, err
-	}
-	return backendInfo, nil
-}
-
-func ParseConfigToMap(config, format string) (map[string]interface{}, error) {
-
-	var flattenMap map[string]interface{}
-	var err error
-
-	if config != "" {
-		var mapConfig map[string]interface{}
-
-		switch format {
-		case "json":
-			if err = json.Unmarshal([]byte(config), &mapConfig); err != nil {
-				log.Printf("[utils.parseConfigToMap] json unmarshal failed: " + err.Error())
-				return nil, err
-			}
-		case "yaml":
-			if err = yaml.Unmarshal([]byte(config), &mapConfig); err != nil {
-				log.Printf("[utils.parseConfigToMap] yaml unmarshal failed: " + err.Error())
-				return nil, err
-			}
-		case "toml":
-			if err = toml.Unmarshal([]byte(config), &mapConfig); err != nil {
-				log.Printf("[utils.parseConfigToMap] toml unmarshal failed: " + err.Error())
-				return nil, err
-			}
-		default:
-			log.Printf("[utils.parseConfigToMap] unsupported format")
-			return nil, errors.New("unsupported format")
-		}
-
-		flattenMap, err = flatten.Flatten(mapConfig, "", flatten.DotStyle)
-		if err != nil {
-			log.Printf("[utils.parseConfigToMap] flatten failed: " + err.Error())
-			return nil, err
-		}
-	}
-
-	return flattenMap, nil
-}
-
-func GetServiceConfigKey(appGroupName, configName string) string {
-	return appGroupName + "_" + configName
-}
-
-func GetServiceConfigKeyPrefix(appGroupName, configName string) string {
-	return appGroupName + "_" + configName + "_" + "keyvalue"
-}
-
-func GetKeyValueConfig(serviceConfig *configproto.Config) *types.KeyValueConfig {
-	flattenPrivate, err := ParseConfigToMap(serviceConfig.Private, serviceConfig.Format)
-	if err != nil {
-		log.Printf("[client.grpc_client] flatten private config failed: " + err.Error())
-		return nil
-	}
-
-	flattenPublic, err := ParseConfigToMap(serviceConfig.Public, serviceConfig.PublicFormat)
-	if err != nil {
-		log.Printf("[client.grpc_client] flatten public config failed: " + err.Error())
-		return nil
-	}
-	flattenServices, err := ParseConfigToMap(serviceConfig.Services, "json")
-	if err != nil {
-		log.Printf("[client.grpc_client] flatten services config failed: " + err.Error())
-		return nil
-	}
-
-	keyValueConfig := &types.KeyValueConfig{
-		Private:       flattenPrivate,
-		Version:       serviceConfig.Version,
-		Public:        flattenPublic,
-		PublicVersion: serviceConfig.PublicVersion,
-		Services:      flattenServices,
-	}
-
-	return keyValueConfig
-}
-
-func GetServiceConfigKeyAddRandom(appGroupName, configName string) string {
-	return appGroupName + "_" + configName + "_" + RandomString(4)
-}
-
-func GetAppGroupNameAndConfigName(serviceConfigkey string) (string, string, error) {
-	arr := strings.Split(serviceConfigkey, "_")
-	if len(arr) < 2 {
-		return "", "", fmt.Errorf("Failed to get app group name and config name from service key")
-	}
-
-	return arr[0], arr[1], nil
-}
-
-// RandomString returns a random string with a fixed length
-func RandomString(n int, allowedChars ...[]rune) string {
-	var defaultLetters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-	var letters []rune
-	if len(allowedChars) == 0 {
-		letters = defaultLetters
-	} else {
-		letters = allowedChars[0]
-	}
-	b := make([]rune, n)
-
-	rand.Seed(time.Now().UnixNano())
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
-	}
-	return string(b)
-}
+package utils
+
+import (
+	"context"
+	"ecm-sdk-go/constants"
+	"ecm-sdk-go/flatten"
+	configproto "ecm-sdk-go/proto"
+	"ecm-sdk-go/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+func GetDefaultAppGroupName() (string, error) {
+	return GetDefaultAppGroupNameContext(context.Background())
+}
+
+// GetDefaultAppGroupNameContext is GetDefaultAppGroupName with a context which bounds the wait for the backend register
+func GetDefaultAppGroupNameContext(ctx context.Context) (string, error) {
+	maxRetryTimes := 3
+
+	backendInfo, err := getBackendInfo(ctx, maxRetryTimes)
+	if err != nil {
+		return "", err
+	}
+	appGroupName := backendInfo.AppGroupConfig.AppGroupName
+
+	return appGroupName, nil
+}
+
+func GetDefaultConfigNames() ([]string, error) {
+	return GetDefaultConfigNamesContext(context.Background())
+}
+
+// GetDefaultConfigNamesContext is GetDefaultConfigNames with a context which bounds the wait for the backend register
+func GetDefaultConfigNamesContext(ctx context.Context) ([]string, error) {
+	configNames := []string{}
+	maxRetryTimes := 3
+
+	backendInfo, err := getBackendInfo(ctx, maxRetryTimes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, configs := range backendInfo.AppGroupConfig.Configs {
+		configNames = append(configNames, configs.ConfigName)
+	}
+
+	return configNames, nil
+}
+
+func ParseBackendInfo(maxRetryTimes int) (string, string, string, error) {
+	return ParseBackendInfoContext(context.Background(), maxRetryTimes)
+}
+
+// ParseBackendInfoContext is ParseBackendInfo with a context which bounds the wait for the backend register
+func ParseBackendInfoContext(ctx context.Context, maxRetryTimes int) (string, string, string, error) {
+
+	backendInfo := &struct {
+		ServiceName string `json:"serviceName"`
+		BackendName string `json:"backendName"`
+		Token       string `json:"token"`
+	}{}
+
+	content, err := readBackendInfoFile(ctx, maxRetryTimes)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if err = json.Unmarshal(content, backendInfo); err != nil {
+		return "", "", "", err
+	}
+	return backendInfo.ServiceName, backendInfo.BackendName, backendInfo.Token, nil
+}
+
+func getBackendInfo(ctx context.Context, maxRetryTimes int) (*types.BackendRegisterResult, error) {
+
+	backendInfo := &types.BackendRegisterResult{}
+
+	content, err := readBackendInfoFile(ctx, maxRetryTimes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(content, backendInfo); err != nil {
+		return nil, err
+	}
+	return backendInfo, nil
+}
+
+func readBackendInfoFile(ctx context.Context, maxRetryTimes int) ([]byte, error) {
+	// wait backend register
+	var content []byte
+	var err error
+	fileName := constants.BackendRegisterInfoPath
+	for i := 0; i < maxRetryTimes; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		content, err = ioutil.ReadFile(fileName)
+		if err != nil {
+			if i == maxRetryTimes-1 {
+				return nil, fmt.Errorf("failed to parse backend information from file:%s, err:%s! ", fileName, err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		} else {
+			break
+		}
+	}
+
+	return content, nil
+}
+
+func ParseConfigToMap(config, format string) (map[string]interface{}, error) {
+
+	var flattenMap map[string]interface{}
+	var err error
+
+	if config != "" {
+		var mapConfig map[string]interface{}
+
+		switch formatName(format) {
+		case "json":
+			if err = json.Unmarshal([]byte(config), &mapConfig); err != nil {
+				log.Printf("[utils.parseConfigToMap] json unmarshal failed: " + err.Error())
+				return nil, err
+			}
+		case "yaml":
+			if err = yaml.Unmarshal([]byte(config), &mapConfig); err != nil {
+				log.Printf("[utils.parseConfigToMap] yaml unmarshal failed: " + err.Error())
+				return nil, err
+			}
+		case "toml":
+			if err = toml.Unmarshal([]byte(config), &mapConfig); err != nil {
+				log.Printf("[utils.parseConfigToMap] toml unmarshal failed: " + err.Error())
+				return nil, err
+			}
+		default:
+			log.Printf("[utils.parseConfigToMap] unsupported format")
+			return nil, errors.New("unsupported format")
+		}
+
+		flattenMap, err = flatten.Flatten(mapConfig, "", flatten.DotStyle)
+		if err != nil {
+			log.Printf("[utils.parseConfigToMap] flatten failed: " + err.Error())
+			return nil, err
+		}
+	}
+
+	return flattenMap, nil
+}
+
+// ParseConfig decodes the config into nested maps with string keys, as json would decode it
+func ParseConfig(config, format string) (map[string]interface{}, error) {
+	var mapConfig map[string]interface{}
+
+	switch formatName(format) {
+	case "json":
+		if err := json.Unmarshal([]byte(config), &mapConfig); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal([]byte(config), &mapConfig); err != nil {
+			return nil, err
+		}
+	case "toml":
+		if err := toml.Unmarshal([]byte(config), &mapConfig); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported format")
+	}
+
+	normalized, _ := normalize(mapConfig).(map[string]interface{})
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+	return normalized, nil
+}
+
+// normalize converts the map[interface{}]interface{} of yaml and the typed slices of toml
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalize(item)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprintf("%v", key)] = normalize(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+func GetServiceConfigKey(appGroupName, configName string) string {
+	return appGroupName + "_" + configName
+}
+
+func GetServiceConfigKeyPrefix(appGroupName, configName string) string {
+	return appGroupName + "_" + configName + "_" + "keyvalue"
+}
+
+func GetKeyValueConfig(serviceConfig *configproto.Config) *types.KeyValueConfig {
+	keyValueConfig, err := ParseKeyValueConfig(serviceConfig)
+	if err != nil {
+		log.Printf("[client.grpc_client] " + err.Error())
+		return nil
+	}
+
+	return keyValueConfig
+}
+
+// ParseKeyValueConfig flattens the private, public and services objects of the config
+func ParseKeyValueConfig(serviceConfig *configproto.Config) (*types.KeyValueConfig, error) {
+	flattenPrivate, err := ParseConfigToMap(serviceConfig.Private, serviceConfig.Format)
+	if err != nil {
+		return nil, errors.New("flatten private config failed: " + err.Error())
+	}
+
+	flattenPublic, err := ParseConfigToMap(serviceConfig.Public, serviceConfig.PublicFormat)
+	if err != nil {
+		return nil, errors.New("flatten public config failed: " + err.Error())
+	}
+	flattenServices, err := ParseConfigToMap(serviceConfig.Services, "json")
+	if err != nil {
+		return nil, errors.New("flatten services config failed: " + err.Error())
+	}
+
+	keyValueConfig := &types.KeyValueConfig{
+		Private:       flattenPrivate,
+		Version:       serviceConfig.Version,
+		Public:        flattenPublic,
+		PublicVersion: serviceConfig.PublicVersion,
+		Services:      flattenServices,
+	}
+
+	return keyValueConfig, nil
+}
+
+func GetServiceConfigKeyAddRandom(appGroupName, configName string) string {
+	return appGroupName + "_" + configName + "_" + RandomString(4)
+}
+
+func GetAppGroupNameAndConfigName(serviceConfigkey string) (string, string, error) {
+	arr := strings.Split(serviceConfigkey, "_")
+	if len(arr) < 2 {
+		return "", "", fmt.Errorf("Failed to get app group name and config name from service key")
+	}
+
+	return arr[0], arr[1], nil
+}
+
+// RandomString returns a random string with a fixed length
+func RandomString(n int, allowedChars ...[]rune) string {
+	var defaultLetters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	var letters []rune
+	if len(allowedChars) == 0 {
+		letters = defaultLetters
+	} else {
+		letters = allowedChars[0]
+	}
+	b := make([]rune, n)
+
+	rand.Seed(time.Now().UnixNano())
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}